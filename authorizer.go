@@ -0,0 +1,39 @@
+package main
+
+import (
+    "context"
+)
+
+// Authorizer is asked before each payload field is merged into the target. Returning an error denies the whole patch.
+type Authorizer interface {
+    Authorize(ctx context.Context, path string, oldValue interface{}, newValue interface{}) error
+}
+
+type AuthorizerFunc func(ctx context.Context, path string, oldValue interface{}, newValue interface{}) error
+
+func (authorizerFunc AuthorizerFunc) Authorize(ctx context.Context, path string, oldValue interface{}, newValue interface{}) error {
+    return authorizerFunc(ctx, path, oldValue, newValue)
+}
+
+type rolesContextKey struct{}
+
+// WithRoles attaches the caller roles checked against fields tagged `patch:"perm=admin|owner"`.
+func WithRoles(ctx context.Context, roles ...string) context.Context {
+    return context.WithValue(ctx, rolesContextKey{}, roles)
+}
+
+func RolesFromContext(ctx context.Context) []string {
+    roles, _ := ctx.Value(rolesContextKey{}).([]string)
+    return roles
+}
+
+func hasAnyRole(roles []string, permissions []string) bool {
+    for _, permission := range permissions {
+        for _, role := range roles {
+            if role == permission {
+                return true
+            }
+        }
+    }
+    return false
+}
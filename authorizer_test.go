@@ -0,0 +1,101 @@
+package main
+
+import (
+    "context"
+    "errors"
+    "reflect"
+    "testing"
+)
+
+type testAccount struct {
+    Name string `json:"name"`
+    Role string `json:"role" patch:"perm=admin|owner"`
+}
+
+func TestPermissionTags(t *testing.T) {
+    account := testAccount{Name: "John", Role: "user"}
+    patcher := NewPatcher()
+
+    err := patcher.PatchContext(WithRoles(context.Background(), "user"), []byte(`{"name": "Richard", "role": "admin"}`), &account)
+    var fieldError *FieldError
+    if !errors.Is(err, ErrForbidden) || !errors.As(err, &fieldError) || fieldError.Path != "/role" {
+        t.Fatalf("expected ErrForbidden at /role, got %v", err)
+    }
+    if account.Name != "John" || account.Role != "user" {
+        t.Fatalf("a denied patch should leave the target untouched, got %+v", account)
+    }
+
+    err = patcher.PatchContext(WithRoles(context.Background(), "owner"), []byte(`{"role": "admin"}`), &account)
+    if err != nil || account.Role != "admin" {
+        t.Fatalf("%v, %+v", err, account)
+    }
+}
+
+func TestPermissionTagsOfSliceItemsAreKept(t *testing.T) {
+    type team struct {
+        Members []testAccount `json:"members"`
+    }
+
+    model := team{Members: []testAccount{{Name: "a", Role: "admin"}}}
+    ctx := WithRoles(context.Background(), "user")
+
+    err := NewPatcher().PatchContext(ctx, []byte(`{"members": [{"name": "b"}]}`), &model)
+    if err != nil || !reflect.DeepEqual(model.Members, []testAccount{{Name: "b", Role: "admin"}}) {
+        t.Fatalf("%v, %+v", err, model.Members)
+    }
+
+    err = NewPatcher().PatchContext(ctx, []byte(`{"members": [{"name": "b"}, {"name": "c"}]}`), &model)
+    if !errors.Is(err, ErrForbidden) {
+        t.Fatalf("expected ErrForbidden, got %v", err)
+    }
+
+    err = NewPatcher().PatchContext(ctx, []byte(`{"members": [{"name": "b", "role": "user"}]}`), &model)
+    var fieldError *FieldError
+    if !errors.Is(err, ErrForbidden) || !errors.As(err, &fieldError) || fieldError.Path != "/members/0/role" {
+        t.Fatalf("expected ErrForbidden at /members/0/role, got %v", err)
+    }
+    if model.Members[0].Role != "admin" {
+        t.Fatalf("a denied patch should leave the target untouched, got %+v", model.Members)
+    }
+}
+
+func TestAuthorizerIsCalledForEachField(t *testing.T) {
+    type call struct {
+        path     string
+        oldValue interface{}
+        newValue interface{}
+    }
+
+    calls := make([]call, 0)
+    denied := errors.New("denied")
+    patcher := NewPatcher(WithAuthorizer(AuthorizerFunc(func(ctx context.Context, path string, oldValue interface{}, newValue interface{}) error {
+        calls = append(calls, call{path, oldValue, newValue})
+        if path == "/address/zip" {
+            return denied
+        }
+        return nil
+    })))
+
+    user := testUser{Name: "John", Address: testAddress{City: "Yangon"}}
+    err := patcher.Patch([]byte(`{"name": "Richard", "address": {"city": "Mandalay"}}`), &user)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    expectedCalls := []call{
+        {"/name", "John", "Richard"},
+        {"/address", testAddress{City: "Yangon"}, map[string]interface{}{"city": "Mandalay"}},
+        {"/address/city", "Yangon", "Mandalay"},
+    }
+    if !reflect.DeepEqual(calls, expectedCalls) {
+        t.Fatalf("expected %+v, got %+v", expectedCalls, calls)
+    }
+
+    err = patcher.Patch([]byte(`{"name": "Ko", "address": {"zip": "1"}}`), &user)
+    if !errors.Is(err, denied) {
+        t.Fatalf("expected the authorizer error, got %v", err)
+    }
+    if user.Name != "Richard" {
+        t.Fatalf("a denied patch should leave the target untouched, got %+v", user)
+    }
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+    "encoding/json"
+    "reflect"
+    "strings"
+)
+
+// Change describes one leaf supplied by the payload. Path is a JSON Pointer built from the json tags.
+// OldState and NewState are only set for an Optional, whose nil value may mean absent or null.
+type Change struct {
+    Path     string      `json:"path"`
+    OldValue interface{} `json:"old"`
+    NewValue interface{} `json:"new"`
+    OldState string      `json:"old_state,omitempty"`
+    NewState string      `json:"new_state,omitempty"`
+}
+
+// ChangeSet separates the leaves whose value was modified from the ones the payload supplied unchanged.
+type ChangeSet struct {
+    Changed   []Change `json:"changed"`
+    Unchanged []Change `json:"unchanged"`
+
+    // allocated holds the paths of nil struct pointers the patch allocated, the inverse patch sets them back to null.
+    allocated []string
+    // versionPath is the path of the `patch:"version"` field, the inverse patch expects its new value.
+    versionPath string
+}
+
+func newChangeSet() *ChangeSet {
+    return &ChangeSet{
+        Changed:   make([]Change, 0),
+        Unchanged: make([]Change, 0),
+    }
+}
+
+func (changes *ChangeSet) HasChanges() bool {
+    return len(changes.Changed) != 0
+}
+
+func (changes *ChangeSet) ChangedPaths() []string {
+    paths := make([]string, 0, len(changes.Changed))
+    for _, change := range changes.Changed {
+        paths = append(paths, change.Path)
+    }
+    return paths
+}
+
+func (changes *ChangeSet) Lookup(path string) (Change, bool) {
+    for _, change := range changes.Changed {
+        if change.Path == path {
+            return change, true
+        }
+    }
+    return Change{}, false
+}
+
+func (changes *ChangeSet) record(path string, oldValue interface{}, newValue interface{}) {
+    if changes == nil {
+        return
+    }
+
+    change := Change{Path: path, OldValue: changeSetValue(oldValue), NewValue: changeSetValue(newValue)}
+    if oldOptional, ok := oldValue.(optionalValuer); ok {
+        change.OldState = oldOptional.optionalChangeSetState()
+    }
+    if newOptional, ok := newValue.(optionalValuer); ok {
+        change.NewState = newOptional.optionalChangeSetState()
+    }
+    if reflect.DeepEqual(oldValue, newValue) {
+        changes.Unchanged = append(changes.Unchanged, change)
+        return
+    }
+    changes.Changed = append(changes.Changed, change)
+}
+
+func (changes *ChangeSet) recordAllocation(path string) {
+    if changes == nil {
+        return
+    }
+    changes.allocated = append(changes.allocated, path)
+}
+
+// InversePatch returns the merge patch restoring the values the patch changed: old values are sent back,
+// added map keys and allocated struct pointers are sent as null. Apply it with the same Patcher and its
+// default NullSetsZero policy. A merge patch cannot leave out a field it has to reset, so an Optional which
+// was absent before the patch (OldState "absent") is restored as null. A version field is sent with its new
+// value, so the undo is checked against the version the patch produced.
+func (changes *ChangeSet) InversePatch() ([]byte, error) {
+    var inversePatch interface{} = make(map[string]interface{})
+
+    for _, change := range changes.Changed {
+        if changes.isUnderAllocation(change.Path) {
+            continue
+        }
+        if changes.versionPath != "" && change.Path == changes.versionPath {
+            inversePatch = setJsonPointerValue(inversePatch, change.Path, change.NewValue)
+            continue
+        }
+        inversePatch = setJsonPointerValue(inversePatch, change.Path, change.OldValue)
+    }
+    for _, path := range changes.allocated {
+        if !changes.isUnderAllocation(path) {
+            inversePatch = setJsonPointerValue(inversePatch, path, nil)
+        }
+    }
+    return json.Marshal(inversePatch)
+}
+
+// isUnderAllocation tells whether path lies below a pointer which did not exist before the patch.
+func (changes *ChangeSet) isUnderAllocation(path string) bool {
+    for _, allocatedPath := range changes.allocated {
+        if strings.HasPrefix(path, allocatedPath+"/") {
+            return true
+        }
+    }
+    return false
+}
+
+// setJsonPointerValue sets value at path in a merge patch document, creating the objects along the way.
+func setJsonPointerValue(document interface{}, path string, value interface{}) interface{} {
+    if path == "" {
+        return value
+    }
+
+    tokens := splitJsonPointer(path)
+    documentMap, ok := document.(map[string]interface{})
+    if !ok {
+        documentMap = make(map[string]interface{})
+    }
+
+    nestedMap := documentMap
+    for _, token := range tokens[:len(tokens)-1] {
+        childMap, ok := nestedMap[token].(map[string]interface{})
+        if !ok {
+            childMap = make(map[string]interface{})
+            nestedMap[token] = childMap
+        }
+        nestedMap = childMap
+    }
+    nestedMap[tokens[len(tokens)-1]] = value
+    return documentMap
+}
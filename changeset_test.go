@@ -0,0 +1,100 @@
+package main
+
+import (
+    "encoding/json"
+    "reflect"
+    "testing"
+)
+
+func TestPatchValuesWithChangeSet(t *testing.T) {
+    user := testUser{Name: "John", Age: 30, Address: testAddress{City: "Yangon"}}
+
+    changes, err := PatchValuesWithChangeSet([]byte(`{"name": "Richard", "age": 30, "address": {"city": "Mandalay"}}`), &user)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    expectedChanged := []Change{
+        {Path: "/name", OldValue: "John", NewValue: "Richard"},
+        {Path: "/address/city", OldValue: "Yangon", NewValue: "Mandalay"},
+    }
+    if !reflect.DeepEqual(changes.Changed, expectedChanged) {
+        t.Fatalf("expected changed %+v, got %+v", expectedChanged, changes.Changed)
+    }
+
+    expectedUnchanged := []Change{{Path: "/age", OldValue: 30, NewValue: 30}}
+    if !reflect.DeepEqual(changes.Unchanged, expectedUnchanged) {
+        t.Fatalf("expected unchanged %+v, got %+v", expectedUnchanged, changes.Unchanged)
+    }
+
+    if !changes.HasChanges() || !reflect.DeepEqual(changes.ChangedPaths(), []string{"/name", "/address/city"}) {
+        t.Fatalf("unexpected changed paths %v", changes.ChangedPaths())
+    }
+    if change, ok := changes.Lookup("/address/city"); !ok || change.NewValue != "Mandalay" {
+        t.Fatalf("unexpected lookup %+v, %v", change, ok)
+    }
+    if _, ok := changes.Lookup("/age"); ok {
+        t.Fatal("an unchanged leaf should not be found by Lookup")
+    }
+}
+
+func TestChangeSetReportsCollectionsAsLeaves(t *testing.T) {
+    user := testUser{Tags: []string{"a"}}
+
+    changes, err := PatchValuesWithChangeSet([]byte(`{"tags": ["a", "b"], "meta": {"k": "v"}}`), &user)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    tagsChange, ok := changes.Lookup("/tags")
+    if !ok || !reflect.DeepEqual(tagsChange.OldValue, []string{"a"}) || !reflect.DeepEqual(tagsChange.NewValue, []string{"a", "b"}) {
+        t.Fatalf("unexpected /tags change %+v", tagsChange)
+    }
+    if _, ok := changes.Lookup("/meta"); !ok {
+        t.Fatalf("expected /meta to be changed, got %v", changes.ChangedPaths())
+    }
+}
+
+func TestChangeSetEscapesPaths(t *testing.T) {
+    type model struct {
+        Ratio string `json:"a/b~c"`
+    }
+
+    changes, err := PatchValuesWithChangeSet([]byte(`{"a/b~c": "x"}`), &model{})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !reflect.DeepEqual(changes.ChangedPaths(), []string{"/a~1b~0c"}) {
+        t.Fatalf("unexpected paths %v", changes.ChangedPaths())
+    }
+}
+
+func TestChangeSetJSON(t *testing.T) {
+    user := testUser{Name: "John", Age: 30}
+
+    changes, err := PatchValuesWithChangeSet([]byte(`{"name": "Richard", "age": 30}`), &user)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    encoded, err := json.Marshal(changes)
+    if err != nil {
+        t.Fatal(err)
+    }
+    expected := `{"changed":[{"path":"/name","old":"John","new":"Richard"}],"unchanged":[{"path":"/age","old":30,"new":30}]}`
+    if string(encoded) != expected {
+        t.Fatalf("expected %s, got %s", expected, encoded)
+    }
+}
+
+func TestFailedPatchReturnsNoChangeSet(t *testing.T) {
+    user := testUser{Name: "John"}
+
+    changes, err := PatchValuesWithChangeSet([]byte(`{"name": "Richard", "age": "old"}`), &user)
+    if err == nil || changes != nil {
+        t.Fatalf("expected an error and no change set, got %v, %+v", err, changes)
+    }
+    if user.Name != "John" {
+        t.Fatalf("a failed patch should leave the target untouched, got %+v", user)
+    }
+}
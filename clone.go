@@ -0,0 +1,56 @@
+package main
+
+import (
+    "reflect"
+)
+
+// cloneReflectValue deep copies maps, slices, pointers and interfaces so a patch applied on the copy never
+// reaches the original. Unexported struct fields are copied shallowly.
+func cloneReflectValue(originalValue reflect.Value) reflect.Value {
+    switch originalValue.Kind() {
+    case reflect.Ptr:
+        if originalValue.IsNil() {
+            return reflect.Zero(originalValue.Type())
+        }
+        clonedValue := reflect.New(originalValue.Type().Elem())
+        clonedValue.Elem().Set(cloneReflectValue(originalValue.Elem()))
+        return clonedValue
+    case reflect.Struct:
+        clonedValue := reflect.New(originalValue.Type()).Elem()
+        clonedValue.Set(originalValue)
+        for index := 0; index < originalValue.NumField(); index += 1 {
+            if !clonedValue.Field(index).CanSet() {
+                continue
+            }
+            clonedValue.Field(index).Set(cloneReflectValue(originalValue.Field(index)))
+        }
+        return clonedValue
+    case reflect.Map:
+        if originalValue.IsNil() {
+            return reflect.Zero(originalValue.Type())
+        }
+        clonedValue := reflect.MakeMapWithSize(originalValue.Type(), originalValue.Len())
+        iterator := originalValue.MapRange()
+        for iterator.Next() {
+            clonedValue.SetMapIndex(iterator.Key(), cloneReflectValue(iterator.Value()))
+        }
+        return clonedValue
+    case reflect.Slice:
+        if originalValue.IsNil() {
+            return reflect.Zero(originalValue.Type())
+        }
+        clonedValue := reflect.MakeSlice(originalValue.Type(), originalValue.Len(), originalValue.Len())
+        for index := 0; index < originalValue.Len(); index += 1 {
+            clonedValue.Index(index).Set(cloneReflectValue(originalValue.Index(index)))
+        }
+        return clonedValue
+    case reflect.Interface:
+        if originalValue.IsNil() {
+            return reflect.Zero(originalValue.Type())
+        }
+        clonedValue := reflect.New(originalValue.Type()).Elem()
+        clonedValue.Set(cloneReflectValue(originalValue.Elem()))
+        return clonedValue
+    }
+    return originalValue
+}
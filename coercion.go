@@ -0,0 +1,105 @@
+package main
+
+import (
+    "fmt"
+    "math"
+    "reflect"
+    "strconv"
+    "strings"
+)
+
+// WithCoercion converts between strings, numbers and bools instead of rejecting a payload of the wrong kind.
+// Fields can opt in one by one with `patch:"coerce"`.
+func WithCoercion(coerce bool) Option {
+    return func(patcher *Patcher) {
+        patcher.coerce = coerce
+    }
+}
+
+func (session *patchSession) shouldCoerce(targetType reflect.Type, fieldTag patchFieldTag) bool {
+    return (session.coerce || fieldTag.coerce) && isScalarKind(targetType.Kind())
+}
+
+func isScalarKind(kind reflect.Kind) bool {
+    switch kind {
+    case reflect.Bool, reflect.String,
+        reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+        reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+        reflect.Float32, reflect.Float64:
+        return true
+    }
+    return false
+}
+
+// coerceScalar is the Converter used in coercion mode. Strings are parsed with strconv, numbers must fit the
+// target without overflow or truncation, and bools map to and from 0 and 1.
+func coerceScalar(targetType reflect.Type, iPayloadValue interface{}) (reflect.Value, error) {
+    coercedReflectValue := reflect.New(targetType).Elem()
+    if iPayloadValue == nil {
+        return coercedReflectValue, nil
+    }
+
+    switch payloadValue := iPayloadValue.(type) {
+    case string:
+        text := strings.TrimSpace(payloadValue)
+        if targetType.Kind() == reflect.String {
+            text = payloadValue
+        }
+        err := parseStringIntoReflectValue(coercedReflectValue, text)
+        return coercedReflectValue, err
+    case bool:
+        switch {
+        case targetType.Kind() == reflect.Bool:
+            coercedReflectValue.SetBool(payloadValue)
+        case targetType.Kind() == reflect.String:
+            coercedReflectValue.SetString(strconv.FormatBool(payloadValue))
+        case payloadValue:
+            return coerceNumber(targetType, 1)
+        default:
+            return coerceNumber(targetType, 0)
+        }
+        return coercedReflectValue, nil
+    case float64:
+        return coerceNumber(targetType, payloadValue)
+    }
+
+    return coercedReflectValue, fmt.Errorf("Unable to coerce %v into %+v: %w", iPayloadValue, targetType, ErrTypeMismatch)
+}
+
+func coerceNumber(targetType reflect.Type, number float64) (reflect.Value, error) {
+    coercedReflectValue := reflect.New(targetType).Elem()
+
+    switch targetType.Kind() {
+    case reflect.String:
+        coercedReflectValue.SetString(strconv.FormatFloat(number, 'f', -1, 64))
+    case reflect.Bool:
+        if number != 0 && number != 1 {
+            return coercedReflectValue, fmt.Errorf("Unable to coerce %v into %+v: %w", number, targetType, ErrTypeMismatch)
+        }
+        coercedReflectValue.SetBool(number == 1)
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+        if number != math.Trunc(number) {
+            return coercedReflectValue, fmt.Errorf("Unable to coerce %v into %+v without truncation: %w", number, targetType, ErrTypeMismatch)
+        }
+        if number < math.MinInt64 || number >= math.MaxInt64 || coercedReflectValue.OverflowInt(int64(number)) {
+            return coercedReflectValue, fmt.Errorf("Value %v overflows %+v: %w", number, targetType, ErrTypeMismatch)
+        }
+        coercedReflectValue.SetInt(int64(number))
+    case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+        if number != math.Trunc(number) {
+            return coercedReflectValue, fmt.Errorf("Unable to coerce %v into %+v without truncation: %w", number, targetType, ErrTypeMismatch)
+        }
+        if number < 0 || number >= math.MaxUint64 || coercedReflectValue.OverflowUint(uint64(number)) {
+            return coercedReflectValue, fmt.Errorf("Value %v overflows %+v: %w", number, targetType, ErrTypeMismatch)
+        }
+        coercedReflectValue.SetUint(uint64(number))
+    case reflect.Float32, reflect.Float64:
+        if coercedReflectValue.OverflowFloat(number) {
+            return coercedReflectValue, fmt.Errorf("Value %v overflows %+v: %w", number, targetType, ErrTypeMismatch)
+        }
+        coercedReflectValue.SetFloat(number)
+    default:
+        return coercedReflectValue, fmt.Errorf("Unable to coerce %v into %+v: %w", number, targetType, ErrTypeMismatch)
+    }
+    return coercedReflectValue, nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+    "errors"
+    "testing"
+)
+
+type testCoerced struct {
+    Count    int     `json:"count"`
+    Small    int8    `json:"small"`
+    Unsigned uint    `json:"unsigned"`
+    Ratio    float32 `json:"ratio"`
+    Enabled  bool    `json:"enabled"`
+    Label    string  `json:"label"`
+    Strict   int     `json:"strict"`
+    Lenient  int     `json:"lenient" patch:"coerce"`
+}
+
+func TestWithCoercion(t *testing.T) {
+    value := testCoerced{}
+    patcher := NewPatcher(WithCoercion(true))
+
+    err := patcher.Patch([]byte(`{"count": " 42 ", "small": 1, "unsigned": "7", "ratio": "0.5", "enabled": 1, "label": 3.5}`), &value)
+    if err != nil {
+        t.Fatal(err)
+    }
+    expected := testCoerced{Count: 42, Small: 1, Unsigned: 7, Ratio: 0.5, Enabled: true, Label: "3.5"}
+    if value != expected {
+        t.Fatalf("expected %+v, got %+v", expected, value)
+    }
+
+    err = patcher.Patch([]byte(`{"enabled": "false", "label": true, "count": true}`), &value)
+    if err != nil || value.Enabled || value.Label != "true" || value.Count != 1 {
+        t.Fatalf("%v, %+v", err, value)
+    }
+}
+
+func TestCoercionRejectsLossyValues(t *testing.T) {
+    patcher := NewPatcher(WithCoercion(true))
+
+    for payload, expectedPath := range map[string]string{
+        `{"small": 200}`:   "/small",
+        `{"small": "200"}`: "/small",
+        `{"unsigned": -1}`: "/unsigned",
+        `{"count": 1.5}`:   "/count",
+        `{"count": "abc"}`: "/count",
+        `{"enabled": 2}`:   "/enabled",
+        `{"ratio": 1e39}`:  "/ratio",
+        `{"count": [1]}`:   "/count",
+    } {
+        value := testCoerced{Count: 5}
+        err := patcher.Patch([]byte(payload), &value)
+        var fieldError *FieldError
+        if !errors.Is(err, ErrTypeMismatch) || !errors.As(err, &fieldError) || fieldError.Path != expectedPath {
+            t.Fatalf("%s: expected ErrTypeMismatch at %s, got %v", payload, expectedPath, err)
+        }
+        if value.Count != 5 {
+            t.Fatalf("%s: a rejected patch should leave the target untouched, got %+v", payload, value)
+        }
+    }
+}
+
+func TestCoerceTag(t *testing.T) {
+    value := testCoerced{}
+
+    err := PatchValues([]byte(`{"lenient": "12"}`), &value)
+    if err != nil || value.Lenient != 12 {
+        t.Fatalf("%v, %+v", err, value)
+    }
+
+    err = PatchValues([]byte(`{"strict": "12"}`), &value)
+    if !errors.Is(err, ErrTypeMismatch) {
+        t.Fatalf("fields without the coerce tag should stay strict, got %v", err)
+    }
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "reflect"
+)
+
+// Compose squashes merge patches into one patch equivalent to applying them in order.
+// A null is kept so the key is still deleted, and a later value replaces it. A merge patch cannot replace a
+// value with a whole object, so an object following a null or a scalar for the same key fails to compose.
+func (patcher *Patcher) Compose(patches ...[]byte) ([]byte, error) {
+    composedPatch, err := patcher.composePatches(patches, nil)
+    if err != nil {
+        return nil, err
+    }
+    return json.Marshal(composedPatch)
+}
+
+// ComposeTyped composes like Compose and checks every patch and the result against a value of targetType,
+// with the Patcher settings but without hooks or the validator.
+func (patcher *Patcher) ComposeTyped(targetType reflect.Type, patches ...[]byte) ([]byte, error) {
+    for targetType.Kind() == reflect.Ptr {
+        targetType = targetType.Elem()
+    }
+    if k := targetType.Kind(); k != reflect.Struct && k != reflect.Slice && k != reflect.Map {
+        return nil, errors.New(fmt.Sprintf("%+v should be the struct, slice or map type.", targetType))
+    }
+
+    checkPatch := func(iPayloadValue interface{}) error {
+        session := patcher.newSession(context.Background())
+        return session.mergePayloadToTarget(reflect.New(targetType).Elem(), iPayloadValue)
+    }
+
+    composedPatch, err := patcher.composePatches(patches, checkPatch)
+    if err != nil {
+        return nil, err
+    }
+    err = checkPatch(composedPatch)
+    if err != nil {
+        return nil, err
+    }
+    return json.Marshal(composedPatch)
+}
+
+func (patcher *Patcher) composePatches(patches [][]byte, checkPatch func(iPayloadValue interface{}) error) (interface{}, error) {
+    var composedPatch interface{} = make(map[string]interface{})
+    session := patcher.newSession(context.Background())
+
+    for index, patch := range patches {
+        var payload interface{}
+        err := json.Unmarshal(patch, &payload)
+        if err != nil {
+            return nil, fmt.Errorf("Patch %d: %w", index, err)
+        }
+
+        if checkPatch != nil {
+            err = checkPatch(payload)
+            if err != nil {
+                return nil, fmt.Errorf("Patch %d: %w", index, err)
+            }
+        }
+
+        if index == 0 {
+            composedPatch = payload
+            continue
+        }
+        composedPatch, err = session.composePatchValues(composedPatch, payload, "")
+        if err != nil {
+            return nil, fmt.Errorf("Patch %d: %w", index, err)
+        }
+    }
+    return composedPatch, nil
+}
+
+// composePatchValues returns the patch applying previousPatch then nextPatch. Objects along the way are copied.
+func (session *patchSession) composePatchValues(previousPatch interface{}, nextPatch interface{}, path string) (interface{}, error) {
+    nextMap, ok := nextPatch.(map[string]interface{})
+    if !ok {
+        return nextPatch, nil
+    }
+
+    previousMap, ok := previousPatch.(map[string]interface{})
+    if !ok {
+        return nil, &FieldError{Path: path, Err: fmt.Errorf("Unable to compose an object after a null or a value: %w", ErrInvalidPatch)}
+    }
+
+    err := session.checkDepth(path)
+    if err != nil {
+        return nil, err
+    }
+
+    composedMap := make(map[string]interface{}, len(previousMap)+len(nextMap))
+    for key, value := range previousMap {
+        composedMap[key] = value
+    }
+    for key, nextValue := range nextMap {
+        previousValue, ok := previousMap[key]
+        if !ok {
+            composedMap[key] = nextValue
+            continue
+        }
+
+        composedValue, err := session.composePatchValues(previousValue, nextValue, appendJsonPointer(path, key))
+        if err != nil {
+            return nil, err
+        }
+        composedMap[key] = composedValue
+    }
+    return composedMap, nil
+}
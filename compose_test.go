@@ -0,0 +1,72 @@
+package main
+
+import (
+    "errors"
+    "reflect"
+    "testing"
+)
+
+func TestCompose(t *testing.T) {
+    patches := [][]byte{
+        []byte(`{"name": "Jane", "address": {"city": "Paris"}, "tags": ["a"]}`),
+        []byte(`{"address": {"zip": "75001"}, "tags": null}`),
+        []byte(`{"age": 31, "tags": ["b"]}`),
+    }
+
+    composedPatch, err := Compose(patches...)
+    if err != nil {
+        t.Fatal(err)
+    }
+    assertJSONEqual(t, `{"name": "Jane", "age": 31, "address": {"city": "Paris", "zip": "75001"}, "tags": ["b"]}`, composedPatch)
+
+    applied := testUser{Name: "John", Meta: map[string]string{"k": "v"}}
+    composed := applied
+    for _, patch := range patches {
+        err = PatchValues(patch, &applied)
+        if err != nil {
+            t.Fatal(err)
+        }
+    }
+    err = PatchValues(composedPatch, &composed)
+    if err != nil || !reflect.DeepEqual(applied, composed) {
+        t.Fatalf("%v: expected %+v, got %+v", err, applied, composed)
+    }
+}
+
+func TestComposeKeepsNull(t *testing.T) {
+    composedPatch, err := Compose([]byte(`{"a": {"b": 1}}`), []byte(`{"a": null}`))
+    if err != nil {
+        t.Fatal(err)
+    }
+    assertJSONEqual(t, `{"a": null}`, composedPatch)
+
+    _, err = Compose([]byte(`{"a": null}`), []byte(`{"a": {"b": 1}}`))
+    var fieldError *FieldError
+    if !errors.As(err, &fieldError) || fieldError.Path != "/a" {
+        t.Fatalf("expected an error at /a, got %v", err)
+    }
+
+    _, err = Compose([]byte(`{}`), []byte(`{`))
+    if err == nil {
+        t.Fatal("expected a syntax error")
+    }
+}
+
+func TestComposeTyped(t *testing.T) {
+    composedPatch, err := ComposeTyped(reflect.TypeOf(&testUser{}), []byte(`{"name": "Jane"}`), []byte(`{"age": 31}`))
+    if err != nil {
+        t.Fatal(err)
+    }
+    assertJSONEqual(t, `{"name": "Jane", "age": 31}`, composedPatch)
+
+    _, err = ComposeTyped(reflect.TypeOf(testUser{}), []byte(`{"name": "Jane"}`), []byte(`{"age": "x"}`))
+    var fieldError *FieldError
+    if !errors.Is(err, ErrTypeMismatch) || !errors.As(err, &fieldError) || fieldError.Path != "/age" {
+        t.Fatalf("expected ErrTypeMismatch at /age, got %v", err)
+    }
+
+    _, err = ComposeTyped(reflect.TypeOf(""), []byte(`{}`))
+    if err == nil {
+        t.Fatal("expected an error for a string type")
+    }
+}
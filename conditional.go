@@ -0,0 +1,130 @@
+package main
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "reflect"
+    "strconv"
+)
+
+// Test is a precondition like the RFC 6902 "test" operation: the value at Path, a JSON Pointer built from the
+// json tags, must equal Value.
+type Test struct {
+    Path  string      `json:"path"`
+    Value interface{} `json:"value"`
+}
+
+// Conditional is a merge patch applied only when every precondition holds, e.g.
+// {"preconditions": [{"path": "/status", "value": "draft"}], "patch": {"title": "New"}}.
+type Conditional struct {
+    Preconditions []Test          `json:"preconditions"`
+    Patch         json.RawMessage `json:"patch"`
+}
+
+// PatchConditional decodes a Conditional from src and applies its patch. When a precondition fails nothing is
+// modified and the error matches ErrPreconditionFailed.
+func (patcher *Patcher) PatchConditional(src []byte, iTargetPointer interface{}) error {
+    return patcher.PatchConditionalContext(context.Background(), src, iTargetPointer)
+}
+
+func (patcher *Patcher) PatchConditionalContext(ctx context.Context, src []byte, iTargetPointer interface{}) error {
+    var conditional Conditional
+    err := json.Unmarshal(src, &conditional)
+    if err != nil {
+        return err
+    }
+    if len(conditional.Patch) == 0 {
+        return fmt.Errorf("Conditional patch should not be empty: %w", ErrInvalidPatch)
+    }
+
+    var payload interface{}
+    err = json.Unmarshal(conditional.Patch, &payload)
+    if err != nil {
+        return err
+    }
+
+    session := patcher.newSession(ctx)
+    session.preconditions = conditional.Preconditions
+    return session.apply(payload, iTargetPointer)
+}
+
+// checkPreconditions compares each Test with the JSON form of the target before anything is merged.
+func (session *patchSession) checkPreconditions(targetReflectValue reflect.Value) error {
+    if len(session.preconditions) == 0 {
+        return nil
+    }
+
+    document, err := session.toDocument(targetReflectValue, "")
+    if err != nil {
+        return err
+    }
+
+    for _, test := range session.preconditions {
+        err := checkDocumentTest(document, test)
+        if err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func checkDocumentTest(document interface{}, test Test) error {
+    actualValue, ok := lookupJsonPointer(document, test.Path)
+    if !ok || !reflect.DeepEqual(plainDocument(actualValue), plainDocument(test.Value)) {
+        return &FieldError{Path: test.Path, Err: &PreconditionFailedError{Expected: test.Value, Actual: plainDocument(actualValue)}}
+    }
+    return nil
+}
+
+// lookupJsonPointer resolves a JSON Pointer in a decoded document.
+func lookupJsonPointer(document interface{}, path string) (interface{}, bool) {
+    if path == "" {
+        return document, true
+    }
+
+    for _, token := range splitJsonPointer(path) {
+        if documentMap, ok := document.(wholeDocument); ok {
+            document = map[string]interface{}(documentMap)
+        }
+
+        switch value := document.(type) {
+        case map[string]interface{}:
+            item, ok := value[token]
+            if !ok {
+                return nil, false
+            }
+            document = item
+        case []interface{}:
+            index, err := strconv.Atoi(token)
+            if err != nil || index < 0 || index >= len(value) {
+                return nil, false
+            }
+            document = value[index]
+        default:
+            return nil, false
+        }
+    }
+    return document, true
+}
+
+// plainDocument turns the maps the diff keeps whole back into plain decoded objects.
+func plainDocument(document interface{}) interface{} {
+    switch value := document.(type) {
+    case wholeDocument:
+        return plainDocument(map[string]interface{}(value))
+    case map[string]interface{}:
+        plainMap := make(map[string]interface{}, len(value))
+        for key, item := range value {
+            plainMap[key] = plainDocument(item)
+        }
+        return plainMap
+    case []interface{}:
+        plainSlice := make([]interface{}, len(value))
+        for index, item := range value {
+            plainSlice[index] = plainDocument(item)
+        }
+        return plainSlice
+    }
+    return document
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+    "errors"
+    "testing"
+)
+
+func TestPatchConditional(t *testing.T) {
+    user := testUser{Name: "John", Tags: []string{"a"}, Address: testAddress{City: "Paris"}}
+
+    err := PatchConditional([]byte(`{"preconditions": [{"path": "/name", "value": "John"}, {"path": "/tags", "value": ["a"]}, {"path": "/address/city", "value": "Paris"}], "patch": {"age": 31}}`), &user)
+    if err != nil || user.Age != 31 {
+        t.Fatalf("%v, %+v", err, user)
+    }
+
+    err = PatchConditional([]byte(`{"preconditions": [{"path": "/name", "value": "Jane"}], "patch": {"age": 32}}`), &user)
+    var preconditionFailedError *PreconditionFailedError
+    if !errors.Is(err, ErrPreconditionFailed) || !errors.As(err, &preconditionFailedError) || preconditionFailedError.Actual != "John" {
+        t.Fatalf("expected a PreconditionFailedError, got %v", err)
+    }
+    if user.Age != 31 {
+        t.Fatalf("a failed precondition should leave the target untouched, got %+v", user)
+    }
+}
+
+func TestPatchConditionalMissingPath(t *testing.T) {
+    user := testUser{Meta: map[string]string{"k": "v"}}
+
+    err := PatchConditional([]byte(`{"preconditions": [{"path": "/meta/k", "value": "v"}], "patch": {"name": "Jane"}}`), &user)
+    if err != nil || user.Name != "Jane" {
+        t.Fatalf("%v, %+v", err, user)
+    }
+
+    err = PatchConditional([]byte(`{"preconditions": [{"path": "/meta/missing", "value": null}], "patch": {"name": "Joan"}}`), &user)
+    var fieldError *FieldError
+    if !errors.Is(err, ErrPreconditionFailed) || !errors.As(err, &fieldError) || fieldError.Path != "/meta/missing" {
+        t.Fatalf("expected ErrPreconditionFailed at /meta/missing, got %v", err)
+    }
+}
+
+func TestPatchConditionalWithoutPatch(t *testing.T) {
+    err := PatchConditional([]byte(`{"preconditions": []}`), &testUser{})
+    if err == nil {
+        t.Fatal("expected an error for a missing patch")
+    }
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+    "errors"
+    "fmt"
+    "reflect"
+    "strconv"
+    "strings"
+    "sync"
+)
+
+// Converter turns a decoded payload value (nil, bool, float64, string, []interface{} or map[string]interface{})
+// into a value of targetType.
+type Converter func(targetType reflect.Type, iPayloadValue interface{}) (reflect.Value, error)
+
+var converterRegistry = struct {
+    sync.RWMutex
+    byType map[reflect.Type]Converter
+    byName map[string]Converter
+}{
+    byType: make(map[reflect.Type]Converter),
+    byName: map[string]Converter{
+        "csv": convertCsvToSlice,
+    },
+}
+
+// RegisterConverter makes every Patcher use converter for fields, slice items and map values of targetType.
+func RegisterConverter(targetType reflect.Type, converter Converter) {
+    converterRegistry.Lock()
+    defer converterRegistry.Unlock()
+    converterRegistry.byType[targetType] = converter
+}
+
+// RegisterNamedConverter makes converter available to fields tagged `patch:"conv=name"`.
+func RegisterNamedConverter(name string, converter Converter) {
+    converterRegistry.Lock()
+    defer converterRegistry.Unlock()
+    converterRegistry.byName[name] = converter
+}
+
+// WithConverter overrides the registered converter of targetType for this Patcher only.
+func WithConverter(targetType reflect.Type, converter Converter) Option {
+    return func(patcher *Patcher) {
+        if patcher.converters == nil {
+            patcher.converters = make(map[reflect.Type]Converter)
+        }
+        patcher.converters[targetType] = converter
+    }
+}
+
+// lookupConverter returns nil when the field is merged by the built-in rules of its kind.
+func (session *patchSession) lookupConverter(targetType reflect.Type, fieldTag patchFieldTag) (Converter, error) {
+    converterRegistry.RLock()
+    defer converterRegistry.RUnlock()
+
+    if fieldTag.converterName != "" {
+        converter, ok := converterRegistry.byName[fieldTag.converterName]
+        if !ok {
+            return nil, errors.New(fmt.Sprintf("Unknown converter %s.", fieldTag.converterName))
+        }
+        return converter, nil
+    }
+
+    if converter, ok := session.converters[targetType]; ok {
+        return converter, nil
+    }
+    return converterRegistry.byType[targetType], nil
+}
+
+// resolveConverter adds the built-in time.Time, time.Duration and coercion converters to the registered ones.
+func (session *patchSession) resolveConverter(targetType reflect.Type, fieldTag patchFieldTag) (Converter, error) {
+    converter, err := session.lookupConverter(targetType, fieldTag)
+    if err != nil || converter != nil {
+        return converter, err
+    }
+
+    switch targetType {
+    case timeType:
+        return timeConverter(fieldTag.layout), nil
+    case durationType:
+        return convertDuration, nil
+    }
+
+    if session.shouldCoerce(targetType, fieldTag) {
+        return coerceScalar, nil
+    }
+    return nil, nil
+}
+
+func mergePayloadWithConverterSF(structFieldValue reflect.Value, iPayloadValue interface{}, converter Converter) error {
+    structFieldDataType, err := helperCheckSettabilityAndSFDataType(structFieldValue)
+    if err != nil {
+        return err
+    }
+
+    convertedReflectValue, err := convertPayloadWithConverter(structFieldDataType, iPayloadValue, converter)
+    if err != nil {
+        return err
+    }
+
+    structFieldValue.Set(convertedReflectValue)
+    return nil
+}
+
+func convertPayloadWithConverter(targetType reflect.Type, iPayloadValue interface{}, converter Converter) (reflect.Value, error) {
+    convertedReflectValue, err := converter(targetType, iPayloadValue)
+    if err != nil {
+        return convertedReflectValue, err
+    }
+
+    if !convertedReflectValue.IsValid() {
+        return reflect.Zero(targetType), nil
+    }
+    if !convertedReflectValue.Type().ConvertibleTo(targetType) {
+        return convertedReflectValue, fmt.Errorf("Converter returned %+v for %+v: %w", convertedReflectValue.Type(), targetType, ErrTypeMismatch)
+    }
+    return convertedReflectValue.Convert(targetType), nil
+}
+
+// convertCsvToSlice accepts "1,2,3" for slices of strings, numbers or bools.
+func convertCsvToSlice(targetType reflect.Type, iPayloadValue interface{}) (reflect.Value, error) {
+    if targetType.Kind() != reflect.Slice {
+        return reflect.Value{}, fmt.Errorf("Converter csv does not support %+v: %w", targetType, ErrTypeMismatch)
+    }
+    if iPayloadValue == nil {
+        return reflect.MakeSlice(targetType, 0, 0), nil
+    }
+
+    csv, ok := iPayloadValue.(string)
+    if !ok {
+        return reflect.Value{}, fmt.Errorf("Invalid payload data for %+v: %w", targetType, ErrTypeMismatch)
+    }
+    if strings.TrimSpace(csv) == "" {
+        return reflect.MakeSlice(targetType, 0, 0), nil
+    }
+
+    items := strings.Split(csv, ",")
+    sliceReflectValue := reflect.MakeSlice(targetType, len(items), len(items))
+    for index, item := range items {
+        itemReflectValue := sliceReflectValue.Index(index)
+        err := parseStringIntoReflectValue(itemReflectValue, strings.TrimSpace(item))
+        if err != nil {
+            return reflect.Value{}, err
+        }
+    }
+    return sliceReflectValue, nil
+}
+
+func parseStringIntoReflectValue(reflectValue reflect.Value, text string) error {
+    switch reflectValue.Kind() {
+    case reflect.String:
+        reflectValue.SetString(text)
+    case reflect.Bool:
+        parsed, err := strconv.ParseBool(text)
+        if err != nil {
+            return fmt.Errorf("Invalid payload data %q for %+v: %w", text, reflectValue.Type(), ErrTypeMismatch)
+        }
+        reflectValue.SetBool(parsed)
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+        parsed, err := strconv.ParseInt(text, 10, reflectValue.Type().Bits())
+        if err != nil {
+            return parseStringError(text, reflectValue.Type(), err)
+        }
+        reflectValue.SetInt(parsed)
+    case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+        parsed, err := strconv.ParseUint(text, 10, reflectValue.Type().Bits())
+        if err != nil {
+            return parseStringError(text, reflectValue.Type(), err)
+        }
+        reflectValue.SetUint(parsed)
+    case reflect.Float32, reflect.Float64:
+        parsed, err := strconv.ParseFloat(text, reflectValue.Type().Bits())
+        if err != nil {
+            return parseStringError(text, reflectValue.Type(), err)
+        }
+        reflectValue.SetFloat(parsed)
+    default:
+        return fmt.Errorf("Unable to parse %q into %+v: %w", text, reflectValue.Type(), ErrTypeMismatch)
+    }
+    return nil
+}
+
+func parseStringError(text string, targetType reflect.Type, err error) error {
+    if errors.Is(err, strconv.ErrRange) {
+        return fmt.Errorf("Value %q overflows %+v: %w", text, targetType, ErrTypeMismatch)
+    }
+    return fmt.Errorf("Invalid payload data %q for %+v: %w", text, targetType, ErrTypeMismatch)
+}
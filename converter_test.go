@@ -0,0 +1,112 @@
+package main
+
+import (
+    "errors"
+    "fmt"
+    "reflect"
+    "strings"
+    "testing"
+)
+
+type testLevel int
+
+const (
+    testLevelLow testLevel = iota
+    testLevelHigh
+)
+
+func convertTestLevel(targetType reflect.Type, iPayloadValue interface{}) (reflect.Value, error) {
+    switch iPayloadValue {
+    case "low":
+        return reflect.ValueOf(testLevelLow), nil
+    case "high":
+        return reflect.ValueOf(testLevelHigh), nil
+    }
+    return reflect.Value{}, fmt.Errorf("Unknown level %v: %w", iPayloadValue, ErrTypeMismatch)
+}
+
+func init() {
+    RegisterConverter(reflect.TypeOf(testLevel(0)), convertTestLevel)
+    RegisterNamedConverter("upper", func(targetType reflect.Type, iPayloadValue interface{}) (reflect.Value, error) {
+        text, _ := iPayloadValue.(string)
+        return reflect.ValueOf(strings.ToUpper(text)), nil
+    })
+}
+
+type testConverted struct {
+    Level  testLevel            `json:"level"`
+    Levels []testLevel          `json:"levels"`
+    ByName map[string]testLevel `json:"by_name"`
+    IDs    []int                `json:"ids" patch:"conv=csv"`
+    Code   string               `json:"code" patch:"conv=upper"`
+}
+
+func TestRegisteredConverter(t *testing.T) {
+    value := testConverted{}
+
+    err := PatchValues([]byte(`{"level": "high", "levels": ["low", "high"], "by_name": {"a": "high"}}`), &value)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if value.Level != testLevelHigh || !reflect.DeepEqual(value.Levels, []testLevel{testLevelLow, testLevelHigh}) || value.ByName["a"] != testLevelHigh {
+        t.Fatalf("unexpected %+v", value)
+    }
+
+    err = PatchValues([]byte(`{"levels": ["low", "max"]}`), &value)
+    var fieldError *FieldError
+    if !errors.Is(err, ErrTypeMismatch) || !errors.As(err, &fieldError) || fieldError.Path != "/levels/1" {
+        t.Fatalf("expected ErrTypeMismatch at /levels/1, got %v", err)
+    }
+}
+
+func TestNamedConverters(t *testing.T) {
+    value := testConverted{}
+
+    err := PatchValues([]byte(`{"ids": "1, 2,3", "code": "ab"}`), &value)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !reflect.DeepEqual(value.IDs, []int{1, 2, 3}) || value.Code != "AB" {
+        t.Fatalf("unexpected %+v", value)
+    }
+
+    err = PatchValues([]byte(`{"ids": "1,x"}`), &value)
+    if !errors.Is(err, ErrTypeMismatch) {
+        t.Fatalf("expected ErrTypeMismatch, got %v", err)
+    }
+}
+
+func TestUnknownNamedConverter(t *testing.T) {
+    type model struct {
+        Name string `json:"name" patch:"conv=missing"`
+    }
+
+    err := PatchValues([]byte(`{"name": "x"}`), &model{})
+    var fieldError *FieldError
+    if !errors.As(err, &fieldError) || fieldError.Path != "/name" {
+        t.Fatalf("expected an error at /name, got %v", err)
+    }
+}
+
+func TestWithConverterOverridesRegistry(t *testing.T) {
+    patcher := NewPatcher(WithConverter(reflect.TypeOf(testLevel(0)), func(targetType reflect.Type, iPayloadValue interface{}) (reflect.Value, error) {
+        return reflect.ValueOf(testLevelLow), nil
+    }))
+
+    value := testConverted{Level: testLevelHigh}
+    err := patcher.Patch([]byte(`{"level": "anything"}`), &value)
+    if err != nil || value.Level != testLevelLow {
+        t.Fatalf("%v, %+v", err, value)
+    }
+}
+
+func TestConverterResultMustFitTheField(t *testing.T) {
+    patcher := NewPatcher(WithConverter(reflect.TypeOf(testLevel(0)), func(targetType reflect.Type, iPayloadValue interface{}) (reflect.Value, error) {
+        return reflect.ValueOf("high"), nil
+    }))
+
+    err := patcher.Patch([]byte(`{"level": "high"}`), &testConverted{})
+    if !errors.Is(err, ErrTypeMismatch) {
+        t.Fatalf("expected ErrTypeMismatch, got %v", err)
+    }
+}
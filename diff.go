@@ -0,0 +1,313 @@
+package main
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "reflect"
+    "sort"
+)
+
+// Operation is one RFC 6902 JSON Patch operation.
+type Operation struct {
+    Op    string      `json:"op"`
+    Path  string      `json:"path"`
+    From  string      `json:"from,omitempty"`
+    Value interface{} `json:"value,omitempty"`
+}
+
+// MarshalJSON keeps a null value on add, replace and test. Remove has no value, move and copy have a from instead.
+func (operation Operation) MarshalJSON() ([]byte, error) {
+    switch operation.Op {
+    case "remove":
+        return json.Marshal(struct {
+            Op   string `json:"op"`
+            Path string `json:"path"`
+        }{operation.Op, operation.Path})
+    case "move", "copy":
+        return json.Marshal(struct {
+            Op   string `json:"op"`
+            Path string `json:"path"`
+            From string `json:"from"`
+        }{operation.Op, operation.Path, operation.From})
+    }
+    return json.Marshal(struct {
+        Op    string      `json:"op"`
+        Path  string      `json:"path"`
+        Value interface{} `json:"value"`
+    }{operation.Op, operation.Path, operation.Value})
+}
+
+// UnmarshalJSON tells a null value from a missing one: add, replace and test need a value, move and copy a from,
+// and every operation a path, otherwise it fails with ErrInvalidPatch (RFC 6902, section 4).
+func (operation *Operation) UnmarshalJSON(data []byte) error {
+    var members struct {
+        Op    string          `json:"op"`
+        Path  *string         `json:"path"`
+        From  *string         `json:"from"`
+        Value json.RawMessage `json:"value"`
+    }
+    err := json.Unmarshal(data, &members)
+    if err != nil {
+        return err
+    }
+
+    *operation = Operation{Op: members.Op}
+    if members.Path == nil {
+        return &FieldError{Path: "", Err: fmt.Errorf("Missing path in %q operation: %w", members.Op, ErrInvalidPatch)}
+    }
+    operation.Path = *members.Path
+
+    switch members.Op {
+    case "add", "replace", "test":
+        if members.Value == nil {
+            return &FieldError{Path: operation.Path, Err: fmt.Errorf("Missing value in %q operation: %w", members.Op, ErrInvalidPatch)}
+        }
+    case "move", "copy":
+        if members.From == nil {
+            return &FieldError{Path: operation.Path, Err: fmt.Errorf("Missing from in %q operation: %w", members.Op, ErrInvalidPatch)}
+        }
+    }
+    if members.From != nil {
+        operation.From = *members.From
+    }
+    if members.Value != nil {
+        return json.Unmarshal(members.Value, &operation.Value)
+    }
+    return nil
+}
+
+// Diff returns the JSON merge patch (RFC 7386) turning oldValue into newValue, named with the Patcher tag.
+// Struct fields are diffed one by one. Maps and slices held by a field are sent whole, the way the patch replaces
+// them, while a top-level map is diffed key by key. A merge patch cannot set null, a field which became null is
+// sent as null and patched back as its zero value. A `patch:"version"` field is left out, so that the patch
+// applies on top of oldValue and bumps its version.
+func (patcher *Patcher) Diff(oldValue interface{}, newValue interface{}) ([]byte, error) {
+    session := patcher.newSession(context.Background())
+    oldDocument, newDocument, err := session.toDocuments(oldValue, newValue)
+    if err != nil {
+        return nil, err
+    }
+
+    mergePatch, changed := diffDocuments(oldDocument, newDocument)
+    if !changed {
+        return []byte("{}"), nil
+    }
+    mergePatch, err = session.withoutVersionKey(reflect.Indirect(reflect.ValueOf(oldValue)), mergePatch)
+    if err != nil {
+        return nil, err
+    }
+    return json.Marshal(mergePatch)
+}
+
+// DiffOperations returns the RFC 6902 operations turning oldValue into newValue, sorted by path. Like Diff, it leaves
+// out a `patch:"version"` field.
+func (patcher *Patcher) DiffOperations(oldValue interface{}, newValue interface{}) ([]Operation, error) {
+    session := patcher.newSession(context.Background())
+    oldDocument, newDocument, err := session.toDocuments(oldValue, newValue)
+    if err != nil {
+        return nil, err
+    }
+    oldDocument, err = session.withoutVersionKey(reflect.Indirect(reflect.ValueOf(oldValue)), oldDocument)
+    if err != nil {
+        return nil, err
+    }
+    newDocument, err = session.withoutVersionKey(reflect.Indirect(reflect.ValueOf(newValue)), newDocument)
+    if err != nil {
+        return nil, err
+    }
+    return diffOperations(oldDocument, newDocument, "", make([]Operation, 0)), nil
+}
+
+// wholeDocument is a map which the diff sends whole instead of key by key.
+type wholeDocument map[string]interface{}
+
+func (session *patchSession) toDocuments(oldValue interface{}, newValue interface{}) (interface{}, interface{}, error) {
+    oldDocument, err := session.toDocument(reflect.ValueOf(oldValue), "")
+    if err != nil {
+        return nil, nil, err
+    }
+    newDocument, err := session.toDocument(reflect.ValueOf(newValue), "")
+    if err != nil {
+        return nil, nil, err
+    }
+    return oldDocument, newDocument, nil
+}
+
+// toDocument decodes a Go value the way the patch names it: struct fields by tag, everything else by its JSON form.
+func (session *patchSession) toDocument(reflectValue reflect.Value, path string) (interface{}, error) {
+    if !reflectValue.IsValid() {
+        return nil, nil
+    }
+
+    err := session.checkDepth(path)
+    if err != nil {
+        return nil, err
+    }
+
+    switch reflectValue.Kind() {
+    case reflect.Interface:
+        if reflectValue.IsNil() {
+            return nil, nil
+        }
+        return session.toDocument(reflectValue.Elem(), path)
+    case reflect.Ptr:
+        if reflectValue.IsNil() {
+            return nil, nil
+        }
+        if isTraversableStructType(reflectValue.Type().Elem()) {
+            return session.toDocument(reflectValue.Elem(), path)
+        }
+    case reflect.Struct:
+        if isTraversableStructType(reflectValue.Type()) {
+            return session.structToDocument(reflectValue, path)
+        }
+    case reflect.Map:
+        if reflectValue.IsNil() {
+            return nil, nil
+        }
+        if reflectValue.Type().Key().Kind() == reflect.String {
+            documentMap := make(map[string]interface{}, reflectValue.Len())
+            iterator := reflectValue.MapRange()
+            for iterator.Next() {
+                key := iterator.Key().String()
+                value, err := session.toDocument(iterator.Value(), appendJsonPointer(path, key))
+                if err != nil {
+                    return nil, err
+                }
+                documentMap[key] = value
+            }
+            if path != "" {
+                return wholeDocument(documentMap), nil
+            }
+            return documentMap, nil
+        }
+    case reflect.Slice:
+        if reflectValue.IsNil() {
+            return nil, nil
+        }
+        if reflectValue.Type().Elem().Kind() != reflect.Uint8 {
+            documentSlice := make([]interface{}, reflectValue.Len())
+            for index := 0; index < reflectValue.Len(); index += 1 {
+                value, err := session.toDocument(reflectValue.Index(index), appendJsonPointerIndex(path, index))
+                if err != nil {
+                    return nil, err
+                }
+                documentSlice[index] = value
+            }
+            return documentSlice, nil
+        }
+    }
+
+    return leafToDocument(reflectValue.Interface(), path)
+}
+
+func (session *patchSession) structToDocument(structReflectValue reflect.Value, path string) (interface{}, error) {
+    documentMap := make(map[string]interface{})
+    for index := 0; index < structReflectValue.NumField(); index += 1 {
+        structField := structReflectValue.Type().Field(index)
+        if structField.PkgPath != "" {
+            continue
+        }
+
+        jsonTag, err := session.getJsonStructTag(structField)
+        if err != nil {
+            return nil, &FieldError{Path: path, Err: err}
+        }
+        if jsonTag == "" || jsonTag == "-" {
+            continue
+        }
+
+        value, err := session.toDocument(structReflectValue.Field(index), appendJsonPointer(path, jsonTag))
+        if err != nil {
+            return nil, err
+        }
+        documentMap[jsonTag] = value
+    }
+    return documentMap, nil
+}
+
+// leafToDocument reports sql and Optional values like change sets do and decodes the rest from their JSON form.
+func leafToDocument(value interface{}, path string) (interface{}, error) {
+    encoded, err := json.Marshal(changeSetValue(value))
+    if err != nil {
+        return nil, &FieldError{Path: path, Err: errors.New(fmt.Sprintf("Unable to encode %+v: %v", reflect.TypeOf(value), err))}
+    }
+
+    var document interface{}
+    err = json.Unmarshal(encoded, &document)
+    if err != nil {
+        return nil, &FieldError{Path: path, Err: err}
+    }
+    return document, nil
+}
+
+// diffDocuments returns the merge patch from oldDocument to newDocument and whether they differ.
+func diffDocuments(oldDocument interface{}, newDocument interface{}) (interface{}, bool) {
+    oldMap, oldIsMap := oldDocument.(map[string]interface{})
+    newMap, newIsMap := newDocument.(map[string]interface{})
+    if !oldIsMap || !newIsMap {
+        return newDocument, !reflect.DeepEqual(oldDocument, newDocument)
+    }
+
+    mergePatch := make(map[string]interface{})
+    for key := range oldMap {
+        if _, ok := newMap[key]; !ok {
+            mergePatch[key] = nil
+        }
+    }
+    for key, newValue := range newMap {
+        oldValue, ok := oldMap[key]
+        if !ok {
+            mergePatch[key] = newValue
+            continue
+        }
+        if valuePatch, changed := diffDocuments(oldValue, newValue); changed {
+            mergePatch[key] = valuePatch
+        }
+    }
+    return mergePatch, len(mergePatch) != 0
+}
+
+func diffOperations(oldDocument interface{}, newDocument interface{}, path string, operations []Operation) []Operation {
+    oldMap, oldIsMap := oldDocument.(map[string]interface{})
+    newMap, newIsMap := newDocument.(map[string]interface{})
+    if !oldIsMap || !newIsMap {
+        if !reflect.DeepEqual(oldDocument, newDocument) {
+            operations = append(operations, Operation{Op: "replace", Path: path, Value: newDocument})
+        }
+        return operations
+    }
+
+    for _, key := range sortedDocumentKeys(oldMap, newMap) {
+        keyPath := appendJsonPointer(path, key)
+        oldValue, inOld := oldMap[key]
+        newValue, inNew := newMap[key]
+
+        switch {
+        case !inNew:
+            operations = append(operations, Operation{Op: "remove", Path: keyPath})
+        case !inOld:
+            operations = append(operations, Operation{Op: "add", Path: keyPath, Value: newValue})
+        default:
+            operations = diffOperations(oldValue, newValue, keyPath, operations)
+        }
+    }
+    return operations
+}
+
+func sortedDocumentKeys(documentMaps ...map[string]interface{}) []string {
+    keys := make([]string, 0)
+    seen := make(map[string]bool)
+    for _, documentMap := range documentMaps {
+        for key := range documentMap {
+            if !seen[key] {
+                seen[key] = true
+                keys = append(keys, key)
+            }
+        }
+    }
+    sort.Strings(keys)
+    return keys
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+    "encoding/json"
+    "reflect"
+    "testing"
+)
+
+func TestDiff(t *testing.T) {
+    oldUser := testUser{Name: "John", Age: 30, Tags: []string{"a"}, Meta: map[string]string{"k": "v", "x": "y"}, Address: testAddress{City: "Paris", Zip: "75001"}}
+    newUser := testUser{Name: "John", Age: 31, Meta: map[string]string{"k": "v"}, Address: testAddress{City: "Lyon", Zip: "75001"}}
+
+    mergePatch, err := Diff(oldUser, newUser)
+    if err != nil {
+        t.Fatal(err)
+    }
+    assertJSONEqual(t, `{"age": 31, "tags": null, "meta": {"k": "v"}, "address": {"city": "Lyon"}}`, mergePatch)
+
+    // null patches a slice back to an empty one
+    patched := oldUser
+    err = PatchValues(mergePatch, &patched)
+    if err != nil {
+        t.Fatal(err)
+    }
+    newUser.Tags = []string{}
+    if !reflect.DeepEqual(patched, newUser) {
+        t.Fatalf("expected %+v, got %+v", newUser, patched)
+    }
+
+    mergePatch, err = Diff(&newUser, &newUser)
+    if err != nil || string(mergePatch) != "{}" {
+        t.Fatalf("%v, %s", err, mergePatch)
+    }
+}
+
+func TestDiffRoundTrips(t *testing.T) {
+    for _, users := range [][2]testUser{
+        {{}, {Name: "John", Tags: []string{"a", "b"}, Meta: map[string]string{"k": "v"}}},
+        {{Name: "John", Tags: []string{"a"}}, {Name: "Jane", Tags: []string{"b", "a"}}},
+        {{Meta: map[string]string{"a": "1"}}, {Meta: map[string]string{"b": "2"}}},
+        {{Tags: []string{"a"}, Address: testAddress{City: "Paris"}}, {Tags: []string{}}},
+    } {
+        mergePatch, err := Diff(users[0], users[1])
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        patched := users[0]
+        err = PatchValues(mergePatch, &patched)
+        if err != nil {
+            t.Fatal(err)
+        }
+        if !reflect.DeepEqual(patched, users[1]) {
+            t.Fatalf("%s: expected %+v, got %+v", mergePatch, users[1], patched)
+        }
+    }
+}
+
+func TestDiffTopLevelMap(t *testing.T) {
+    mergePatch, err := Diff(map[string]testAddress{"a": {City: "Paris"}, "b": {}}, map[string]testAddress{"a": {City: "Lyon"}})
+    if err != nil {
+        t.Fatal(err)
+    }
+    assertJSONEqual(t, `{"a": {"city": "Lyon"}, "b": null}`, mergePatch)
+}
+
+func TestDiffOperations(t *testing.T) {
+    oldUser := testUser{Name: "John", Meta: map[string]string{"k": "v"}}
+    newUser := testUser{Name: "Jane", Address: testAddress{City: "Paris"}}
+
+    operations, err := DiffOperations(oldUser, newUser)
+    if err != nil {
+        t.Fatal(err)
+    }
+    expected := []Operation{
+        {Op: "replace", Path: "/address/city", Value: "Paris"},
+        {Op: "replace", Path: "/meta", Value: nil},
+        {Op: "replace", Path: "/name", Value: "Jane"},
+    }
+    if !reflect.DeepEqual(operations, expected) {
+        t.Fatalf("expected %+v, got %+v", expected, operations)
+    }
+
+    encoded, err := json.Marshal(operations)
+    if err != nil {
+        t.Fatal(err)
+    }
+    patched := oldUser
+    err = PatchOperations(encoded, &patched)
+    newUser.Meta = map[string]string{}
+    if err != nil || !reflect.DeepEqual(patched, newUser) {
+        t.Fatalf("%v: expected %+v, got %+v", err, newUser, patched)
+    }
+}
+
+func TestDiffUsesPatcherTag(t *testing.T) {
+    type model struct {
+        Name string `api:"full_name"`
+    }
+
+    mergePatch, err := NewPatcher(WithTagName("api")).Diff(model{Name: "a"}, model{Name: "b"})
+    if err != nil {
+        t.Fatal(err)
+    }
+    assertJSONEqual(t, `{"full_name": "b"}`, mergePatch)
+}
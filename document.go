@@ -0,0 +1,99 @@
+package main
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+)
+
+// PatchDocument applies a JSON merge patch (RFC 7386) to a schemaless JSON document, e.g. a json.RawMessage.
+// A null in src deletes the key under NullSetsZero, keeps it under NullIgnored and fails under NullRejected.
+func (patcher *Patcher) PatchDocument(doc []byte, src []byte) ([]byte, error) {
+    var document interface{}
+    if len(doc) != 0 {
+        err := json.Unmarshal(doc, &document)
+        if err != nil {
+            return nil, err
+        }
+    }
+
+    var payload interface{}
+    err := json.Unmarshal(src, &payload)
+    if err != nil {
+        return nil, err
+    }
+
+    mergedDocument, err := patcher.newSession(context.Background()).mergePayloadToDocument(document, payload, "")
+    if err != nil {
+        return nil, err
+    }
+    return json.Marshal(mergedDocument)
+}
+
+// PatchMapDocument applies src to a decoded document. The map is replaced only when the whole patch succeeded.
+// Unlike PatchMap, which takes an already decoded patch for a struct, the map here is the patched document.
+func (patcher *Patcher) PatchMapDocument(target *map[string]interface{}, src []byte) error {
+    if target == nil {
+        return errors.New("Target map pointer should not be nil.")
+    }
+
+    payloadMap := make(map[string]interface{})
+    err := json.Unmarshal(src, &payloadMap)
+    if err != nil {
+        return err
+    }
+
+    mergedDocument, err := patcher.newSession(context.Background()).mergePayloadToDocument(*target, payloadMap, "")
+    if err != nil {
+        return err
+    }
+
+    *target = mergedDocument.(map[string]interface{})
+    return nil
+}
+
+// mergePayloadToDocument returns the merged document. Objects along the patched paths are copied, never mutated.
+func (session *patchSession) mergePayloadToDocument(document interface{}, iPayloadValue interface{}, path string) (interface{}, error) {
+    payloadMap, ok := iPayloadValue.(map[string]interface{})
+    if !ok {
+        session.changes.record(path, document, iPayloadValue)
+        return iPayloadValue, nil
+    }
+
+    err := session.checkDepth(path)
+    if err != nil {
+        return nil, err
+    }
+
+    documentMap := make(map[string]interface{})
+    if existingMap, ok := document.(map[string]interface{}); ok {
+        for key, value := range existingMap {
+            documentMap[key] = value
+        }
+    }
+
+    for key, payloadValue := range payloadMap {
+        keyPath := appendJsonPointer(path, key)
+
+        if payloadValue == nil {
+            switch session.nullPolicy {
+            case NullIgnored:
+            case NullRejected:
+                return nil, &FieldError{Path: keyPath, Err: ErrNullRejected}
+            default:
+                if existingValue, ok := documentMap[key]; ok {
+                    session.changes.record(keyPath, existingValue, nil)
+                    delete(documentMap, key)
+                }
+            }
+            continue
+        }
+
+        mergedValue, err := session.mergePayloadToDocument(documentMap[key], payloadValue, keyPath)
+        if err != nil {
+            return nil, err
+        }
+        documentMap[key] = mergedValue
+    }
+    return documentMap, nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+    "encoding/json"
+    "errors"
+    "reflect"
+    "testing"
+)
+
+func assertJSONEqual(t *testing.T, expected string, actual []byte) {
+    t.Helper()
+
+    var expectedDocument, actualDocument interface{}
+    if err := json.Unmarshal([]byte(expected), &expectedDocument); err != nil {
+        t.Fatal(err)
+    }
+    if err := json.Unmarshal(actual, &actualDocument); err != nil {
+        t.Fatalf("invalid JSON %s: %v", actual, err)
+    }
+    if !reflect.DeepEqual(expectedDocument, actualDocument) {
+        t.Fatalf("expected %s, got %s", expected, actual)
+    }
+}
+
+func TestPatchDocument(t *testing.T) {
+    merged, err := PatchDocument([]byte(`{"a": 1, "b": {"c": 2, "d": 3}, "e": [1]}`), []byte(`{"a": null, "b": {"c": 4}, "e": {"f": 5}}`))
+    if err != nil {
+        t.Fatal(err)
+    }
+    assertJSONEqual(t, `{"b": {"c": 4, "d": 3}, "e": {"f": 5}}`, merged)
+
+    merged, err = PatchDocument(nil, []byte(`{"a": {"b": null}}`))
+    if err != nil {
+        t.Fatal(err)
+    }
+    assertJSONEqual(t, `{"a": {}}`, merged)
+
+    merged, err = PatchDocument([]byte(`{"a": 1}`), []byte(`[1, 2]`))
+    if err != nil {
+        t.Fatal(err)
+    }
+    assertJSONEqual(t, `[1, 2]`, merged)
+}
+
+func TestPatchDocumentNullPolicies(t *testing.T) {
+    merged, err := NewPatcher(WithNullPolicy(NullIgnored)).PatchDocument([]byte(`{"a": 1}`), []byte(`{"a": null}`))
+    if err != nil {
+        t.Fatal(err)
+    }
+    assertJSONEqual(t, `{"a": 1}`, merged)
+
+    _, err = NewPatcher(WithNullPolicy(NullRejected)).PatchDocument([]byte(`{"a": {"b": 1}}`), []byte(`{"a": {"b": null}}`))
+    var fieldError *FieldError
+    if !errors.Is(err, ErrNullRejected) || !errors.As(err, &fieldError) || fieldError.Path != "/a/b" {
+        t.Fatalf("expected ErrNullRejected at /a/b, got %v", err)
+    }
+}
+
+func TestPatchDocumentMaxDepth(t *testing.T) {
+    _, err := NewPatcher(WithMaxDepth(1)).PatchDocument([]byte(`{}`), []byte(`{"a": {"b": {"c": 1}}}`))
+    if !errors.Is(err, ErrMaxDepth) {
+        t.Fatalf("expected ErrMaxDepth, got %v", err)
+    }
+}
+
+func TestPatchMapDocument(t *testing.T) {
+    nested := map[string]interface{}{"c": 2.0}
+    target := map[string]interface{}{"a": 1.0, "b": nested}
+
+    err := PatchMapDocument(&target, []byte(`{"a": null, "b": {"c": 3}, "d": "x"}`))
+    if err != nil {
+        t.Fatal(err)
+    }
+    expected := map[string]interface{}{"b": map[string]interface{}{"c": 3.0}, "d": "x"}
+    if !reflect.DeepEqual(target, expected) {
+        t.Fatalf("expected %v, got %v", expected, target)
+    }
+    if nested["c"] != 2.0 {
+        t.Fatalf("nested maps should be copied, got %v", nested)
+    }
+
+    err = NewPatcher(WithNullPolicy(NullRejected)).PatchMapDocument(&target, []byte(`{"d": "y", "b": null}`))
+    if !errors.Is(err, ErrNullRejected) || target["d"] != "x" {
+        t.Fatalf("a failed patch should leave the map untouched, got %v, %v", err, target)
+    }
+
+    err = PatchMapDocument(nil, []byte(`{}`))
+    if err == nil {
+        t.Fatal("expected an error for a nil map pointer")
+    }
+}
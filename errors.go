@@ -0,0 +1,133 @@
+package main
+
+import (
+    "errors"
+    "fmt"
+    "strings"
+)
+
+var (
+    ErrTypeMismatch       = errors.New("incompatible for merging")
+    ErrNullRejected       = errors.New("null is not allowed")
+    ErrReadOnlyField      = errors.New("field is read-only")
+    ErrForbidden          = errors.New("field is not allowed to be modified")
+    ErrValidation         = errors.New("validation failed")
+    ErrMergeConflict      = errors.New("both sides changed the same field")
+    ErrVersionConflict    = errors.New("version does not match")
+    ErrPreconditionFailed = errors.New("precondition failed")
+    ErrInvalidPatch       = errors.New("patch is invalid")
+    ErrNotFound           = errors.New("resource not found")
+    ErrUnknownField       = errors.New("field does not exist")
+    ErrUnsupportedType    = errors.New("type is not supported")
+    ErrMaxDepth           = errors.New("payload is nested too deeply")
+)
+
+// FieldError ties an error to the JSON Pointer of the payload field which caused it.
+type FieldError struct {
+    Path string
+    Err  error
+}
+
+func (fieldError *FieldError) Error() string {
+    if fieldError.Path == "" {
+        return fieldError.Err.Error()
+    }
+    return fmt.Sprintf("%s: %s", fieldError.Path, fieldError.Err)
+}
+
+func (fieldError *FieldError) Unwrap() error {
+    return fieldError.Err
+}
+
+// FieldErrors collects every FieldError found by a Validator.
+type FieldErrors []*FieldError
+
+func (fieldErrors FieldErrors) Error() string {
+    messages := make([]string, 0, len(fieldErrors))
+    for _, fieldError := range fieldErrors {
+        messages = append(messages, fieldError.Error())
+    }
+    return strings.Join(messages, "; ")
+}
+
+func (fieldErrors FieldErrors) Unwrap() []error {
+    errs := make([]error, 0, len(fieldErrors))
+    for _, fieldError := range fieldErrors {
+        errs = append(errs, fieldError)
+    }
+    return errs
+}
+
+// ValidationError is the cause of a FieldError reported by TagValidator. It matches ErrValidation.
+type ValidationError struct {
+    Rule    string
+    Message string
+}
+
+func (validationError *ValidationError) Error() string {
+    return validationError.Message
+}
+
+func (validationError *ValidationError) Is(target error) bool {
+    return target == ErrValidation
+}
+
+// MergeConflictError lists the paths both sides of a three-way merge changed differently. It matches ErrMergeConflict.
+type MergeConflictError struct {
+    Conflicts []Conflict
+}
+
+func (mergeConflictError *MergeConflictError) Error() string {
+    paths := make([]string, 0, len(mergeConflictError.Conflicts))
+    for _, conflict := range mergeConflictError.Conflicts {
+        paths = append(paths, conflict.Path)
+    }
+    return fmt.Sprintf("Merge conflicts at %s.", strings.Join(paths, ", "))
+}
+
+func (mergeConflictError *MergeConflictError) Is(target error) bool {
+    return target == ErrMergeConflict
+}
+
+// VersionConflictError reports the version the patch expected and the one the target holds. It matches ErrVersionConflict.
+type VersionConflictError struct {
+    Expected interface{}
+    Actual   interface{}
+}
+
+func (versionConflictError *VersionConflictError) Error() string {
+    return fmt.Sprintf("Version %v does not match the current version %v.", versionConflictError.Expected, versionConflictError.Actual)
+}
+
+func (versionConflictError *VersionConflictError) Is(target error) bool {
+    return target == ErrVersionConflict
+}
+
+// PreconditionFailedError reports a Test which did not hold. Actual is nil when the path does not exist.
+// It matches ErrPreconditionFailed.
+type PreconditionFailedError struct {
+    Expected interface{}
+    Actual   interface{}
+}
+
+func (preconditionFailedError *PreconditionFailedError) Error() string {
+    return fmt.Sprintf("Expected %v but found %v.", preconditionFailedError.Expected, preconditionFailedError.Actual)
+}
+
+func (preconditionFailedError *PreconditionFailedError) Is(target error) bool {
+    return target == ErrPreconditionFailed
+}
+
+// wrapFieldError attaches path to err unless err already knows the path it belongs to.
+func wrapFieldError(path string, err error) error {
+    if err == nil {
+        return nil
+    }
+
+    var fieldError *FieldError
+    var fieldErrors FieldErrors
+    if errors.As(err, &fieldError) || errors.As(err, &fieldErrors) {
+        return err
+    }
+    return &FieldError{Path: path, Err: err}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "reflect"
+    "sort"
+    "strings"
+)
+
+// FieldMask mirrors google.protobuf.FieldMask. Paths are the json tag names joined with dots, e.g. "address.city".
+type FieldMask struct {
+    Paths []string `json:"paths"`
+}
+
+// FieldMaskFromPayload lists the leaves a merge patch sets on modelType. Nested objects of struct fields are
+// followed, any other value, including null, selects the field itself. Unknown keys fail in strict mode.
+func (patcher *Patcher) FieldMaskFromPayload(src []byte, modelType reflect.Type) (FieldMask, error) {
+    payloadMap := make(map[string]interface{})
+    err := json.Unmarshal(src, &payloadMap)
+    if err != nil {
+        return FieldMask{}, err
+    }
+
+    for modelType.Kind() == reflect.Ptr {
+        modelType = modelType.Elem()
+    }
+    if !isTraversableStructType(modelType) {
+        return FieldMask{}, fmt.Errorf("%+v should be the struct type: %w", modelType, ErrUnsupportedType)
+    }
+
+    paths := make([]string, 0)
+    err = patcher.newSession(context.Background()).collectFieldMaskPaths(modelType, payloadMap, "", "", &paths)
+    if err != nil {
+        return FieldMask{}, err
+    }
+    sort.Strings(paths)
+    return FieldMask{Paths: paths}, nil
+}
+
+func (session *patchSession) collectFieldMaskPaths(structType reflect.Type, payloadMap map[string]interface{}, maskPath string, path string, paths *[]string) error {
+    err := session.checkDepth(path)
+    if err != nil {
+        return err
+    }
+
+    matchedPayloadKeys := make(map[string]bool, len(payloadMap))
+    for index := 0; index < structType.NumField(); index += 1 {
+        structField := structType.Field(index)
+        structFieldJsonTag, err := session.getJsonStructTag(structField)
+        if err != nil {
+            return err
+        }
+        iPayloadValue, ok := payloadMap[structFieldJsonTag]
+        if structFieldJsonTag == "" || !ok {
+            continue
+        }
+        matchedPayloadKeys[structFieldJsonTag] = true
+
+        structFieldMaskPath := structFieldJsonTag
+        if maskPath != "" {
+            structFieldMaskPath = maskPath + "." + structFieldJsonTag
+        }
+
+        structFieldType := structField.Type
+        for structFieldType.Kind() == reflect.Ptr {
+            structFieldType = structFieldType.Elem()
+        }
+        nestedPayloadMap, isMap := iPayloadValue.(map[string]interface{})
+        if !isMap || !isTraversableStructType(structFieldType) {
+            *paths = append(*paths, structFieldMaskPath)
+            continue
+        }
+
+        err = session.collectFieldMaskPaths(structFieldType, nestedPayloadMap, structFieldMaskPath, appendJsonPointer(path, structFieldJsonTag), paths)
+        if err != nil {
+            return err
+        }
+    }
+
+    if session.strict {
+        for payloadKey := range payloadMap {
+            if !matchedPayloadKeys[payloadKey] {
+                return &FieldError{Path: appendJsonPointer(path, payloadKey), Err: ErrUnknownField}
+            }
+        }
+    }
+    return nil
+}
+
+// MergePatchFromFieldMask returns the merge patch setting the fields of mask to their value in src.
+// A path below a nil pointer of src sets that pointer to null. Paths cannot select map entries.
+func (patcher *Patcher) MergePatchFromFieldMask(mask FieldMask, src interface{}) ([]byte, error) {
+    document, err := patcher.newSession(context.Background()).toDocument(reflect.ValueOf(src), "")
+    if err != nil {
+        return nil, err
+    }
+
+    var mergePatch interface{} = make(map[string]interface{})
+    for _, maskPath := range normalizeFieldMaskPaths(mask.Paths) {
+        path, value, err := lookupFieldMaskPath(document, maskPath)
+        if err != nil {
+            return nil, err
+        }
+        mergePatch = setJsonPointerValue(mergePatch, path, plainDocument(value))
+    }
+    return json.Marshal(mergePatch)
+}
+
+// ApplyFieldMask copies the fields of mask from src to dst, a pointer to the same struct type, through the Patcher:
+// read-only fields, authorization and the validator apply, hooks do not. A version field in the mask is copied
+// like any other field, and the version of dst is neither checked nor incremented.
+func (patcher *Patcher) ApplyFieldMask(mask FieldMask, src interface{}, dst interface{}) error {
+    mergePatch, err := patcher.MergePatchFromFieldMask(mask, src)
+    if err != nil {
+        return err
+    }
+
+    var payload interface{}
+    err = json.Unmarshal(mergePatch, &payload)
+    if err != nil {
+        return err
+    }
+
+    session := patcher.newSession(context.Background())
+    session.skipHooks = true
+    session.copyVersion = true
+    return session.apply(payload, dst)
+}
+
+// normalizeFieldMaskPaths sorts the paths and drops the ones already covered by a shorter path.
+func normalizeFieldMaskPaths(maskPaths []string) []string {
+    sortedPaths := append([]string(nil), maskPaths...)
+    sort.Strings(sortedPaths)
+
+    normalizedPaths := make([]string, 0, len(sortedPaths))
+    for _, maskPath := range sortedPaths {
+        if count := len(normalizedPaths); count != 0 {
+            previousPath := normalizedPaths[count-1]
+            if maskPath == previousPath || strings.HasPrefix(maskPath, previousPath+".") {
+                continue
+            }
+        }
+        normalizedPaths = append(normalizedPaths, maskPath)
+    }
+    return normalizedPaths
+}
+
+// lookupFieldMaskPath returns the JSON Pointer of a mask path and its value. The pointer stops at a nil parent.
+func lookupFieldMaskPath(document interface{}, maskPath string) (string, interface{}, error) {
+    path := ""
+    for _, token := range strings.Split(maskPath, ".") {
+        path = appendJsonPointer(path, token)
+
+        switch value := document.(type) {
+        case nil:
+            return trimLastJsonPointerToken(path), nil, nil
+        case map[string]interface{}:
+            item, ok := value[token]
+            if !ok {
+                return "", nil, &FieldError{Path: path, Err: ErrUnknownField}
+            }
+            document = item
+        default:
+            return "", nil, &FieldError{Path: path, Err: fmt.Errorf("Field mask path %s selects an entry of a map or a value: %w", maskPath, ErrUnknownField)}
+        }
+    }
+    return path, document, nil
+}
+
+func trimLastJsonPointerToken(path string) string {
+    return path[:strings.LastIndex(path, "/")]
+}
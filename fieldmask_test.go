@@ -0,0 +1,102 @@
+package main
+
+import (
+    "errors"
+    "reflect"
+    "testing"
+)
+
+type testContact struct {
+    Name    string       `json:"name"`
+    Email   string       `json:"email" validate:"max=10"`
+    Address *testAddress `json:"address"`
+    Created string       `json:"created" patch:"readonly"`
+    Version int          `json:"version" patch:"version"`
+}
+
+func TestFieldMaskFromPayload(t *testing.T) {
+    mask, err := FieldMaskFromPayload([]byte(`{"name": "a", "address": {"city": "Paris"}, "tags": null, "meta": {"k": "v"}}`), reflect.TypeOf(&testUser{}))
+    if err != nil {
+        t.Fatal(err)
+    }
+    expected := []string{"address.city", "meta", "name", "tags"}
+    if !reflect.DeepEqual(mask.Paths, expected) {
+        t.Fatalf("expected %v, got %v", expected, mask.Paths)
+    }
+
+    _, err = NewPatcher(WithStrict(true)).FieldMaskFromPayload([]byte(`{"address": {"street": "x"}}`), reflect.TypeOf(testUser{}))
+    var fieldError *FieldError
+    if !errors.Is(err, ErrUnknownField) || !errors.As(err, &fieldError) || fieldError.Path != "/address/street" {
+        t.Fatalf("expected ErrUnknownField at /address/street, got %v", err)
+    }
+}
+
+func TestMergePatchFromFieldMask(t *testing.T) {
+    src := testContact{Name: "Jane", Email: "j@x", Address: &testAddress{City: "Paris", Zip: "75001"}}
+
+    mergePatch, err := MergePatchFromFieldMask(FieldMask{Paths: []string{"address.city", "name", "address"}}, src)
+    if err != nil {
+        t.Fatal(err)
+    }
+    assertJSONEqual(t, `{"name": "Jane", "address": {"city": "Paris", "zip": "75001"}}`, mergePatch)
+
+    mergePatch, err = MergePatchFromFieldMask(FieldMask{Paths: []string{"address.city"}}, testContact{})
+    if err != nil {
+        t.Fatal(err)
+    }
+    assertJSONEqual(t, `{"address": null}`, mergePatch)
+
+    _, err = MergePatchFromFieldMask(FieldMask{Paths: []string{"phone"}}, src)
+    if !errors.Is(err, ErrUnknownField) {
+        t.Fatalf("expected ErrUnknownField, got %v", err)
+    }
+}
+
+func TestApplyFieldMask(t *testing.T) {
+    src := testContact{Name: "Jane", Email: "jane@x", Address: &testAddress{City: "Paris"}, Created: "today"}
+    dst := testContact{Name: "John", Email: "john@x", Address: &testAddress{City: "Lyon", Zip: "69001"}, Created: "yesterday"}
+
+    err := ApplyFieldMask(FieldMask{Paths: []string{"name", "address.city", "created"}}, &src, &dst)
+    if err != nil {
+        t.Fatal(err)
+    }
+    expected := testContact{Name: "Jane", Email: "john@x", Address: &testAddress{City: "Paris", Zip: "69001"}, Created: "yesterday"}
+    if !reflect.DeepEqual(dst, expected) {
+        t.Fatalf("expected %+v, got %+v", expected, dst)
+    }
+    if src.Address.City != "Paris" || src.Name != "Jane" {
+        t.Fatalf("src should be left untouched, got %+v", src)
+    }
+}
+
+func TestApplyFieldMaskCopiesTheVersion(t *testing.T) {
+    src := testContact{Name: "Jane", Version: 7}
+    dst := testContact{Name: "John", Version: 3}
+
+    err := ApplyFieldMask(FieldMask{Paths: []string{"name", "version"}}, src, &dst)
+    if err != nil || dst.Name != "Jane" || dst.Version != 7 {
+        t.Fatalf("%v, %+v", err, dst)
+    }
+
+    err = ApplyFieldMask(FieldMask{Paths: []string{"name"}}, testContact{Name: "Joan"}, &dst)
+    if err != nil || dst.Name != "Joan" || dst.Version != 7 {
+        t.Fatalf("the version of dst should not be incremented, got %v, %+v", err, dst)
+    }
+}
+
+func TestApplyFieldMaskRules(t *testing.T) {
+    dst := testContact{Email: "a@x"}
+    patcher := NewPatcher(WithValidator(NewTagValidator()))
+
+    err := patcher.ApplyFieldMask(FieldMask{Paths: []string{"email"}}, testContact{Email: "much-too-long@x"}, &dst)
+    if !errors.Is(err, ErrValidation) || dst.Email != "a@x" {
+        t.Fatalf("expected ErrValidation, got %v, %+v", err, dst)
+    }
+
+    testHookCalls = nil
+    person := testHookedPerson{FirstName: "John"}
+    err = ApplyFieldMask(FieldMask{Paths: []string{"first_name"}}, testHookedPerson{FirstName: "Jane"}, &person)
+    if err != nil || person.FirstName != "Jane" || len(testHookCalls) != 0 {
+        t.Fatalf("ApplyFieldMask should not call hooks, got %v, %v", err, testHookCalls)
+    }
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "mime"
+    "net/http"
+    "reflect"
+    "strings"
+)
+
+const (
+    mergePatchMediaType = "application/merge-patch+json"
+    jsonPatchMediaType  = "application/json-patch+json"
+    jsonMediaType       = "application/json"
+
+    defaultMaxBodyBytes = 1 << 20
+)
+
+type handlerConfig struct {
+    patcher      *Patcher
+    maxBodyBytes int64
+}
+
+type HandlerOption func(config *handlerConfig)
+
+// WithHandlerPatcher patches with patcher instead of the default one.
+func WithHandlerPatcher(patcher *Patcher) HandlerOption {
+    return func(config *handlerConfig) {
+        config.patcher = patcher
+    }
+}
+
+// WithMaxBodyBytes limits the size of the request body. Default is 1 MiB.
+func WithMaxBodyBytes(maxBodyBytes int64) HandlerOption {
+    return func(config *handlerConfig) {
+        config.maxBodyBytes = maxBodyBytes
+    }
+}
+
+// Handler serves PATCH requests: it loads the resource, applies the body and saves the result.
+// application/merge-patch+json and application/json are merge patches, application/json-patch+json is an
+// RFC 6902 JSON Patch. An If-Match header is checked against the ETag of the loaded value, and the patched
+// value is sent back with its new ETag. Errors are written as application/problem+json; load and save may
+// return ErrNotFound or any sentinel error of this package to choose the status code, and a nil pointer
+// returned by load is a 404. A failed If-Match is a 412, a failed "test" operation in the body a 409.
+func Handler[T any](load func(r *http.Request) (T, error), save func(r *http.Request, value T) error, options ...HandlerOption) http.Handler {
+    config := handlerConfig{patcher: defaultPatcher, maxBodyBytes: defaultMaxBodyBytes}
+    for _, option := range options {
+        option(&config)
+    }
+
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.Method != http.MethodPatch {
+            w.Header().Set("Allow", http.MethodPatch)
+            writeProblem(w, http.StatusMethodNotAllowed, errors.New("Only PATCH is supported."))
+            return
+        }
+
+        mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+        if err != nil || (mediaType != mergePatchMediaType && mediaType != jsonPatchMediaType && mediaType != jsonMediaType) {
+            w.Header().Set("Accept-Patch", strings.Join([]string{mergePatchMediaType, jsonPatchMediaType, jsonMediaType}, ", "))
+            writeProblem(w, http.StatusUnsupportedMediaType, errors.New(fmt.Sprintf("Unsupported Content-Type %q.", r.Header.Get("Content-Type"))))
+            return
+        }
+
+        body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, config.maxBodyBytes))
+        if err != nil {
+            var maxBytesError *http.MaxBytesError
+            if errors.As(err, &maxBytesError) {
+                writeProblem(w, http.StatusRequestEntityTooLarge, err)
+                return
+            }
+            writeProblem(w, http.StatusBadRequest, err)
+            return
+        }
+
+        value, err := load(r)
+        if err != nil {
+            writeProblem(w, problemStatus(err, http.StatusInternalServerError), err)
+            return
+        }
+
+        // A pointer T is patched in place, a nil one means load found nothing.
+        var iTargetPointer interface{} = &value
+        if valueReflectValue := reflect.ValueOf(&value).Elem(); valueReflectValue.Kind() == reflect.Ptr {
+            if valueReflectValue.IsNil() {
+                writeProblem(w, http.StatusNotFound, ErrNotFound)
+                return
+            }
+            iTargetPointer = value
+        }
+
+        if ifMatch := r.Header.Get("If-Match"); ifMatch != "" {
+            matched, err := MatchesETag(ifMatch, value)
+            if err != nil {
+                writeProblem(w, http.StatusInternalServerError, err)
+                return
+            }
+            if !matched {
+                writeProblem(w, http.StatusPreconditionFailed, ErrPreconditionFailed)
+                return
+            }
+        }
+
+        if mediaType == jsonPatchMediaType {
+            err = config.patcher.PatchOperationsContext(r.Context(), body, iTargetPointer)
+        } else {
+            err = config.patcher.PatchContext(r.Context(), body, iTargetPointer)
+        }
+        if err != nil {
+            writeProblem(w, problemStatus(err, http.StatusUnprocessableEntity), err)
+            return
+        }
+
+        err = save(r, value)
+        if err != nil {
+            writeProblem(w, problemStatus(err, http.StatusInternalServerError), err)
+            return
+        }
+
+        etag, err := ETag(value)
+        if err != nil {
+            writeProblem(w, http.StatusInternalServerError, err)
+            return
+        }
+        w.Header().Set("ETag", etag)
+        w.Header().Set("Content-Type", jsonMediaType)
+        json.NewEncoder(w).Encode(value)
+    })
+}
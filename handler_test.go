@@ -0,0 +1,169 @@
+package main
+
+import (
+    "encoding/json"
+    "errors"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+)
+
+type testStore struct {
+    user  *testUser
+    saved int
+}
+
+func (store *testStore) handler(options ...HandlerOption) http.Handler {
+    load := func(r *http.Request) (*testUser, error) {
+        return store.user, nil
+    }
+    save := func(r *http.Request, user *testUser) error {
+        store.saved += 1
+        return nil
+    }
+    return Handler(load, save, options...)
+}
+
+func serveTestPatch(handler http.Handler, method string, contentType string, body string, headers map[string]string) *httptest.ResponseRecorder {
+    request := httptest.NewRequest(method, "/users/1", strings.NewReader(body))
+    request.Header.Set("Content-Type", contentType)
+    for key, value := range headers {
+        request.Header.Set(key, value)
+    }
+
+    recorder := httptest.NewRecorder()
+    handler.ServeHTTP(recorder, request)
+    return recorder
+}
+
+func decodeTestProblem(t *testing.T, recorder *httptest.ResponseRecorder) Problem {
+    t.Helper()
+
+    if contentType := recorder.Header().Get("Content-Type"); contentType != problemMediaType {
+        t.Fatalf("expected %s, got %s", problemMediaType, contentType)
+    }
+    var problem Problem
+    err := json.Unmarshal(recorder.Body.Bytes(), &problem)
+    if err != nil {
+        t.Fatal(err)
+    }
+    return problem
+}
+
+func TestHandlerMergePatch(t *testing.T) {
+    store := &testStore{user: &testUser{Name: "John", Age: 30}}
+
+    recorder := serveTestPatch(store.handler(), http.MethodPatch, mergePatchMediaType, `{"age": 31}`, nil)
+    if recorder.Code != http.StatusOK || store.user.Age != 31 || store.saved != 1 {
+        t.Fatalf("unexpected %d %s, %+v", recorder.Code, recorder.Body, store.user)
+    }
+    etag, _ := ETag(store.user)
+    if recorder.Header().Get("ETag") != etag {
+        t.Fatalf("expected ETag %s, got %s", etag, recorder.Header().Get("ETag"))
+    }
+    assertJSONEqual(t, `{"name": "John", "age": 31, "tags": null, "meta": null, "address": {"city": "", "zip": ""}}`, recorder.Body.Bytes())
+}
+
+func TestHandlerJSONPatch(t *testing.T) {
+    store := &testStore{user: &testUser{Name: "John"}}
+
+    recorder := serveTestPatch(store.handler(), http.MethodPatch, jsonPatchMediaType+"; charset=utf-8", `[{"op": "replace", "path": "/name", "value": "Jane"}]`, nil)
+    if recorder.Code != http.StatusOK || store.user.Name != "Jane" {
+        t.Fatalf("unexpected %d %s", recorder.Code, recorder.Body)
+    }
+
+    recorder = serveTestPatch(store.handler(), http.MethodPatch, jsonPatchMediaType, `[{"op": "test", "path": "/name", "value": "John"}, {"op": "replace", "path": "/name", "value": "Joan"}]`, nil)
+    problem := decodeTestProblem(t, recorder)
+    if recorder.Code != http.StatusConflict || problem.Status != http.StatusConflict || store.user.Name != "Jane" || store.saved != 1 {
+        t.Fatalf("a failed test operation should be a 409, got %d %+v", recorder.Code, problem)
+    }
+    if len(problem.Errors) != 1 || problem.Errors[0].Path != "/name" {
+        t.Fatalf("unexpected errors %+v", problem.Errors)
+    }
+}
+
+func TestHandlerIfMatch(t *testing.T) {
+    store := &testStore{user: &testUser{Name: "John"}}
+    etag, _ := ETag(store.user)
+
+    recorder := serveTestPatch(store.handler(), http.MethodPatch, mergePatchMediaType, `{"age": 1}`, map[string]string{"If-Match": `"stale"`})
+    if recorder.Code != http.StatusPreconditionFailed || store.user.Age != 0 {
+        t.Fatalf("expected a 412, got %d", recorder.Code)
+    }
+
+    recorder = serveTestPatch(store.handler(), http.MethodPatch, mergePatchMediaType, `{"age": 1}`, map[string]string{"If-Match": etag})
+    if recorder.Code != http.StatusOK || store.user.Age != 1 {
+        t.Fatalf("expected a 200, got %d %s", recorder.Code, recorder.Body)
+    }
+}
+
+func TestHandlerRejectsRequests(t *testing.T) {
+    store := &testStore{user: &testUser{}}
+
+    recorder := serveTestPatch(store.handler(), http.MethodPut, mergePatchMediaType, `{}`, nil)
+    if recorder.Code != http.StatusMethodNotAllowed || recorder.Header().Get("Allow") != http.MethodPatch {
+        t.Fatalf("expected a 405, got %d", recorder.Code)
+    }
+
+    recorder = serveTestPatch(store.handler(), http.MethodPatch, "text/plain", `{}`, nil)
+    if recorder.Code != http.StatusUnsupportedMediaType || recorder.Header().Get("Accept-Patch") == "" {
+        t.Fatalf("expected a 415, got %d", recorder.Code)
+    }
+
+    recorder = serveTestPatch(store.handler(WithMaxBodyBytes(8)), http.MethodPatch, mergePatchMediaType, `{"name": "too long"}`, nil)
+    if recorder.Code != http.StatusRequestEntityTooLarge {
+        t.Fatalf("expected a 413, got %d", recorder.Code)
+    }
+
+    recorder = serveTestPatch(store.handler(), http.MethodPatch, mergePatchMediaType, `{"age": "x"}`, nil)
+    problem := decodeTestProblem(t, recorder)
+    if recorder.Code != http.StatusUnprocessableEntity || len(problem.Errors) != 1 || problem.Errors[0].Path != "/age" {
+        t.Fatalf("expected a 422 at /age, got %d %+v", recorder.Code, problem)
+    }
+
+    recorder = serveTestPatch(store.handler(), http.MethodPatch, mergePatchMediaType, `{"age":`, nil)
+    if recorder.Code != http.StatusBadRequest {
+        t.Fatalf("expected a 400, got %d", recorder.Code)
+    }
+    if store.saved != 0 {
+        t.Fatalf("rejected requests should not be saved, got %d", store.saved)
+    }
+}
+
+func TestHandlerNilResource(t *testing.T) {
+    store := &testStore{}
+
+    recorder := serveTestPatch(store.handler(), http.MethodPatch, mergePatchMediaType, `{"age": 1}`, nil)
+    if recorder.Code != http.StatusNotFound || store.saved != 0 {
+        t.Fatalf("expected a 404, got %d", recorder.Code)
+    }
+}
+
+func TestHandlerHidesServerErrors(t *testing.T) {
+    handler := Handler(func(r *http.Request) (testUser, error) {
+        return testUser{}, nil
+    }, func(r *http.Request, user testUser) error {
+        return errors.New("pq: connection refused to 10.0.0.7")
+    })
+
+    recorder := serveTestPatch(handler, http.MethodPatch, mergePatchMediaType, `{"age": 1}`, nil)
+    problem := decodeTestProblem(t, recorder)
+    if recorder.Code != http.StatusInternalServerError || problem.Detail != serverErrorDetail || strings.Contains(recorder.Body.String(), "pq:") {
+        t.Fatalf("expected a generic 500, got %d %s", recorder.Code, recorder.Body)
+    }
+}
+
+func TestHandlerLoadErrors(t *testing.T) {
+    handler := Handler(func(r *http.Request) (testUser, error) {
+        return testUser{}, ErrNotFound
+    }, func(r *http.Request, user testUser) error {
+        return nil
+    })
+
+    recorder := serveTestPatch(handler, http.MethodPatch, mergePatchMediaType, `{}`, nil)
+    problem := decodeTestProblem(t, recorder)
+    if recorder.Code != http.StatusNotFound || problem.Title != http.StatusText(http.StatusNotFound) {
+        t.Fatalf("expected a 404, got %d %+v", recorder.Code, problem)
+    }
+}
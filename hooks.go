@@ -0,0 +1,32 @@
+package main
+
+import (
+    "context"
+)
+
+// BeforePatcher is implemented by models which want to veto a patch. BeforePatch is called on a copy of the target,
+// still holding its current values, with the changes about to be written. The copy is read-only: what the hook
+// writes to it is discarded, derived fields belong in AfterPatch.
+type BeforePatcher interface {
+    BeforePatch(ctx context.Context, changes *ChangeSet) error
+}
+
+// AfterPatcher is implemented by models which recompute derived fields. AfterPatch is called on the patched copy
+// before the validator, so the derived fields are validated too, and returning an error discards the patch.
+type AfterPatcher interface {
+    AfterPatch(ctx context.Context, changes *ChangeSet) error
+}
+
+func callBeforePatch(ctx context.Context, iStructPointer interface{}, changes *ChangeSet) error {
+    if beforePatcher, ok := iStructPointer.(BeforePatcher); ok {
+        return beforePatcher.BeforePatch(ctx, changes)
+    }
+    return nil
+}
+
+func callAfterPatch(ctx context.Context, iStructPointer interface{}, changes *ChangeSet) error {
+    if afterPatcher, ok := iStructPointer.(AfterPatcher); ok {
+        return afterPatcher.AfterPatch(ctx, changes)
+    }
+    return nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+    "context"
+    "errors"
+    "reflect"
+    "testing"
+)
+
+type testHookedPerson struct {
+    FirstName string `json:"first_name" validate:"max=5"`
+    LastName  string `json:"last_name"`
+    FullName  string `json:"full_name" patch:"readonly" validate:"max=12"`
+}
+
+var testHookCalls []string
+
+func (person *testHookedPerson) BeforePatch(ctx context.Context, changes *ChangeSet) error {
+    testHookCalls = append(testHookCalls, "before "+person.FirstName)
+    if _, ok := changes.Lookup("/last_name"); ok && person.LastName == "Locked" {
+        return errors.New("last name is locked")
+    }
+    return nil
+}
+
+func (person *testHookedPerson) AfterPatch(ctx context.Context, changes *ChangeSet) error {
+    person.FullName = person.FirstName + " " + person.LastName
+    testHookCalls = append(testHookCalls, "after "+person.FirstName)
+    return nil
+}
+
+func TestHooksRecomputeDerivedFields(t *testing.T) {
+    testHookCalls = nil
+    person := testHookedPerson{FirstName: "John", LastName: "Doe"}
+
+    err := PatchValues([]byte(`{"first_name": "Jane"}`), &person)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if person.FullName != "Jane Doe" {
+        t.Fatalf("unexpected %+v", person)
+    }
+    if !reflect.DeepEqual(testHookCalls, []string{"before John", "after Jane"}) {
+        t.Fatalf("unexpected calls %v", testHookCalls)
+    }
+}
+
+func TestBeforePatchVetoesPatch(t *testing.T) {
+    testHookCalls = nil
+    person := testHookedPerson{FirstName: "John", LastName: "Locked"}
+
+    err := PatchValues([]byte(`{"first_name": "Jane", "last_name": "Doe"}`), &person)
+    if err == nil || person.FirstName != "John" || person.LastName != "Locked" {
+        t.Fatalf("expected a vetoed patch, got %v, %+v", err, person)
+    }
+}
+
+func TestDerivedFieldsAreValidated(t *testing.T) {
+    testHookCalls = nil
+    person := testHookedPerson{FirstName: "John", LastName: "Doe"}
+    patcher := NewPatcher(WithValidator(NewTagValidator()))
+
+    err := patcher.Patch([]byte(`{"last_name": "Doe-Smithson"}`), &person)
+    var fieldError *FieldError
+    if !errors.Is(err, ErrValidation) || !errors.As(err, &fieldError) || fieldError.Path != "/full_name" {
+        t.Fatalf("expected ErrValidation at /full_name, got %v", err)
+    }
+    if !reflect.DeepEqual(testHookCalls, []string{"before John", "after John"}) {
+        t.Fatalf("the validator should run after the hooks, got %v", testHookCalls)
+    }
+    if person.LastName != "Doe" || person.FullName != "" {
+        t.Fatalf("a rejected patch should leave the target untouched, got %+v", person)
+    }
+}
+
+type testScribblingPerson struct {
+    Name string `json:"name"`
+}
+
+func (person *testScribblingPerson) BeforePatch(ctx context.Context, changes *ChangeSet) error {
+    person.Name = "scribbled"
+    return errors.New("vetoed")
+}
+
+func TestBeforePatchIsReadOnly(t *testing.T) {
+    person := testScribblingPerson{Name: "John"}
+
+    err := PatchValues([]byte(`{"name": "Jane"}`), &person)
+    if err == nil || person.Name != "John" {
+        t.Fatalf("what BeforePatch writes should be discarded, got %v, %+v", err, person)
+    }
+}
+
+func TestPreviewSkipsHooks(t *testing.T) {
+    testHookCalls = nil
+    person := testHookedPerson{FirstName: "John", LastName: "Doe"}
+
+    preview, _, err := Preview([]byte(`{"first_name": "Jane"}`), &person)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(testHookCalls) != 0 || preview.(*testHookedPerson).FullName != "" {
+        t.Fatalf("Preview should not call hooks, got %v", testHookCalls)
+    }
+}
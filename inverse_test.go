@@ -0,0 +1,59 @@
+package main
+
+import (
+    "reflect"
+    "testing"
+)
+
+type testProfile struct {
+    Name    string            `json:"name"`
+    Labels  map[string]string `json:"labels"`
+    Tags    []string          `json:"tags"`
+    Address *testAddress      `json:"address"`
+}
+
+func TestInversePatchUndoesThePatch(t *testing.T) {
+    original := testProfile{Name: "John", Labels: map[string]string{"a": "1"}, Tags: []string{"x", "y"}}
+    profile := original
+
+    inversePatch, err := PatchValuesWithInverse([]byte(`{"name": "Jane", "labels": {"b": "2"}, "tags": ["z"], "address": {"city": "Paris"}}`), &profile)
+    if err != nil {
+        t.Fatal(err)
+    }
+    assertJSONEqual(t, `{"name": "John", "labels": {"a": "1"}, "tags": ["x", "y"], "address": null}`, inversePatch)
+    if profile.Address == nil || profile.Address.City != "Paris" || profile.Labels["b"] != "2" {
+        t.Fatalf("unexpected patched %+v", profile)
+    }
+
+    err = PatchValues(inversePatch, &profile)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !reflect.DeepEqual(profile, original) {
+        t.Fatalf("expected %+v, got %+v", original, profile)
+    }
+}
+
+func TestInversePatchRestoresNestedValues(t *testing.T) {
+    profile := testProfile{Address: &testAddress{City: "Paris", Zip: "75001"}}
+
+    inversePatch, err := PatchValuesWithInverse([]byte(`{"address": {"city": "Lyon", "zip": "75001"}}`), &profile)
+    if err != nil {
+        t.Fatal(err)
+    }
+    assertJSONEqual(t, `{"address": {"city": "Paris"}}`, inversePatch)
+
+    err = PatchValues(inversePatch, &profile)
+    if err != nil || *profile.Address != (testAddress{City: "Paris", Zip: "75001"}) {
+        t.Fatalf("%v, %+v", err, profile.Address)
+    }
+}
+
+func TestInversePatchOfNoChange(t *testing.T) {
+    profile := testProfile{Name: "John"}
+
+    inversePatch, err := PatchValuesWithInverse([]byte(`{"name": "John"}`), &profile)
+    if err != nil || string(inversePatch) != "{}" {
+        t.Fatalf("%v, %s", err, inversePatch)
+    }
+}
@@ -1,7 +1,6 @@
 package main
 
 import (
-    "encoding/json"
     "errors"
     "fmt"
     "reflect"
@@ -9,455 +8,83 @@ import (
 )
 
 func PatchValues(src []byte, iStructPointer interface{}) error {
-    payloadMap := make(map[string]interface{})
-
-    err := json.Unmarshal(src, &payloadMap)
-    if err != nil {
-        return err
-    }
-
-    structReflectValue, err := getReflectValueFromIStructPointer(iStructPointer)
-    if err != nil {
-        return err
-    }
-
-    err = traverseStructAndMergeStructFieldsWithPayload(structReflectValue, payloadMap)
-    if err != nil {
-        return err
-    }
-
-    return nil
+    return defaultPatcher.Patch(src, iStructPointer)
 }
 
-func getReflectValueFromIStructPointer(iStructPointer interface{}) (ret reflect.Value, err error) {
-    valueOfIStructPointer := reflect.ValueOf(iStructPointer)
-    typeOfIStructPointer := reflect.TypeOf(iStructPointer)
-    // Read Third Law here: https://blog.golang.org/laws-of-reflection
-    // Pointer is needed as a patch operation would require mutation.
-    // A direct call to Elem results in panic, thus the if statement block below.
-    if k := valueOfIStructPointer.Kind(); k != reflect.Ptr {
-        err = errors.New(fmt.Sprintf("%+v should be the pointer of struct.", typeOfIStructPointer))
-        return
-    }
-
-    valueOfIStructPointerElem := valueOfIStructPointer.Elem()
-
-    if k := valueOfIStructPointerElem.Type().Kind(); k != reflect.Struct {
-        err = errors.New(fmt.Sprintf("%+v should be the struct type.", typeOfIStructPointer))
-        return
-    }
-
-    // Below is a further (and definitive) check regarding settability in addition to checking whether it is a pointer earlier.
-    if !valueOfIStructPointerElem.CanSet() {
-        err = errors.New(fmt.Sprintf("%+v is unable to set the values.", typeOfIStructPointer))
-        return
-    }
-
-    ret = valueOfIStructPointerElem
-
-    return
+func PatchValuesWithChangeSet(src []byte, iStructPointer interface{}) (*ChangeSet, error) {
+    return defaultPatcher.PatchWithChangeSet(src, iStructPointer)
 }
 
-func traverseStructAndMergeStructFieldsWithPayload(structReflectValue reflect.Value, payloadMap map[string]interface{}) error {
-    for index := 0; index < structReflectValue.NumField(); index += 1 {
-        structField := structReflectValue.Type().Field(index)
-        structFieldJsonTag, err := getJsonStructTag(structField)
-        if err != nil {
-            return err
-        }
-
-        if iPayloadValue, ok := payloadMap[structFieldJsonTag]; ok {
-            structFieldValue := structReflectValue.Field(index)
-            err := mergePayloadToStructField(structFieldValue, iPayloadValue)
-            if err != nil {
-                return err
-            }
-        }
-    }
-    return nil
+func PatchValuesWithInverse(src []byte, iStructPointer interface{}) ([]byte, error) {
+    return defaultPatcher.PatchWithInverse(src, iStructPointer)
 }
 
-func mergePayloadToStructField(structFieldValue reflect.Value, iPayloadValue interface{}) (err error) {
-
-    structFieldDataType := structFieldValue.Kind()
-
-    switch structFieldDataType {
-    case reflect.Struct:
-        return mergePayloadToStructSF(structFieldValue, iPayloadValue)
-    case reflect.Map:
-        return mergePayloadToMapSF(structFieldValue, iPayloadValue)
-    case reflect.Slice:
-        return mergePayloadToSliceSF(structFieldValue, iPayloadValue)
-    case reflect.Interface:
-        return mergePayloadToInterfaceSF(structFieldValue, iPayloadValue)
-    case reflect.Bool:
-        return mergePayloadToBoolSF(structFieldValue, iPayloadValue)
-    case reflect.String:
-        return mergePayloadToStringSF(structFieldValue, iPayloadValue)
-    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
-        reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
-        reflect.Float32, reflect.Float64:
-        return mergePayloadToNumberSF(structFieldValue, iPayloadValue)
-    }
-    err = errors.New(fmt.Sprintf("Unsupported type %+v.", structFieldDataType))
-    return
+func PatchConditional(src []byte, iStructPointer interface{}) error {
+    return defaultPatcher.PatchConditional(src, iStructPointer)
 }
 
-// TODO:: need to fix for null struct
-func mergePayloadToStructSF(structFieldValue reflect.Value, iPayloadValue interface{}) error {
-    structFieldDataType, err := helperCheckSettabilityAndSFDataType(structFieldValue)
-    if err != nil {
-        return err
-    }
-
-    payloadMap, ok := iPayloadValue.(map[string]interface{})
-    if !ok {
-        return errors.New(fmt.Sprintf("Invalid payload data for %+v: incompatible for merging.", structFieldDataType))
-    }
-
-    err = traverseStructAndMergeStructFieldsWithPayload(structFieldValue, payloadMap)
-    if err != nil {
-        return err
-    }
-    return nil
+func PatchOperations(src []byte, iStructPointer interface{}) error {
+    return defaultPatcher.PatchOperations(src, iStructPointer)
 }
 
-func mergePayloadToMapSF(structFieldValue reflect.Value, iPayloadValue interface{}) error {
-    structFieldDataType, err := helperCheckSettabilityAndSFDataType(structFieldValue)
-    if err != nil {
-        return err
-    }
-
-    structFieldType := structFieldValue.Type()
-
-    var mapReflectValue reflect.Value
-    if iPayloadValue == nil {
-        mapReflectValue = reflect.MakeMap(structFieldType)
-    } else {
-        if payloadKind := reflect.ValueOf(iPayloadValue).Kind(); payloadKind != reflect.Map {
-            return errors.New(fmt.Sprintf("Invalid payload data for %+v: incompatible for merging", structFieldDataType))
-        }
-
-        mapReflectValue, err = getNewReflectValueMapWithPayloadValues(structFieldValue.Type(), iPayloadValue)
-        if err != nil {
-            return err
-        }
-    }
-
-    structFieldValue.Set(mapReflectValue)
-    return nil
+func ApplyOperations(doc []byte, src []byte) ([]byte, error) {
+    return defaultPatcher.ApplyOperations(doc, src)
 }
 
-func mergePayloadToInterfaceSF(structFieldValue reflect.Value, iPayloadValue interface{}) error {
-    _, err := helperCheckSettabilityAndSFDataType(structFieldValue)
-    if err != nil {
-        return err
-    }
-
-    structFieldValue.Set(reflect.ValueOf(iPayloadValue))
-    return nil
+func Preview(src []byte, iStructPointer interface{}) (interface{}, *ChangeSet, error) {
+    return defaultPatcher.Preview(src, iStructPointer)
 }
 
-func mergePayloadToBoolSF(structFieldValue reflect.Value, iPayloadValue interface{}) error {
-    structFieldDataType, err := helperCheckSettabilityAndSFDataType(structFieldValue)
-    if err != nil {
-        return err
-    }
-
-    if iPayloadValue == nil {
-        structFieldValue.SetBool(false)
-    } else {
-        if reflect.ValueOf(iPayloadValue).Kind() != reflect.Bool {
-            return errors.New(fmt.Sprintf("Invalid payload data for %+v: incompatible for merging", structFieldDataType))
-        }
-        structFieldValue.Set(reflect.ValueOf(iPayloadValue).Convert(structFieldValue.Type()))
-    }
-
-    return nil
+func PatchDocument(doc []byte, src []byte) ([]byte, error) {
+    return defaultPatcher.PatchDocument(doc, src)
 }
 
-func mergePayloadToStringSF(structFieldValue reflect.Value, iPayloadValue interface{}) error {
-    structFieldDataType, err := helperCheckSettabilityAndSFDataType(structFieldValue)
-    if err != nil {
-        return err
-    }
-
-    if iPayloadValue == nil {
-        structFieldValue.SetString("")
-    } else {
-        if reflect.ValueOf(iPayloadValue).Kind() != reflect.String {
-            return errors.New(fmt.Sprintf("Invalid payload data for %+v: incompatible for merging", structFieldDataType))
-        }
-        structFieldValue.Set(reflect.ValueOf(iPayloadValue).Convert(structFieldValue.Type()))
-    }
-
-    return nil
+func PatchMapDocument(target *map[string]interface{}, src []byte) error {
+    return defaultPatcher.PatchMapDocument(target, src)
 }
 
-func mergePayloadToNumberSF(structFieldValue reflect.Value, iPayloadValue interface{}) error {
-    structFieldDataType, err := helperCheckSettabilityAndSFDataType(structFieldValue)
-    if err != nil {
-        return err
-    }
-
-    if iPayloadValue == nil {
-        structFieldValue.Set(reflect.ValueOf(0).Convert(structFieldValue.Type()))
-    } else {
-        if !isNumericValue(iPayloadValue) {
-            return errors.New(fmt.Sprintf("Invalid payload data for %+v: incompatible for merging", structFieldDataType))
-        }
-        structFieldValue.Set(reflect.ValueOf(iPayloadValue).Convert(structFieldValue.Type()))
-    }
-
-    return nil
+func Diff(oldValue interface{}, newValue interface{}) ([]byte, error) {
+    return defaultPatcher.Diff(oldValue, newValue)
 }
 
-func mergePayloadToSliceSF(structFieldValue reflect.Value, iPayloadValue interface{}) error {
-    structFieldDataType, err := helperCheckSettabilityAndSFDataType(structFieldValue)
-    if err != nil {
-        return err
-    }
-    var sliceReflectValue reflect.Value
-    if iPayloadValue == nil {
-        emptyInterfaceSlice := make([]interface{}, 0)
-        sliceReflectValue = makeNewSlice(structFieldDataType, emptyInterfaceSlice)
-    }else{
-        if payloadKind := reflect.ValueOf(iPayloadValue).Kind(); payloadKind != reflect.Slice {
-            return errors.New(fmt.Sprintf("Invalid payload data for %+v: incompatible for merging", structFieldDataType))
-        }
-
-        sliceReflectValue, err = getNewReflectValueSliceWithPayloadValues(structFieldValue, iPayloadValue)
-        if err != nil {
-            return err
-        }
-    }
-    structFieldValue.Set(sliceReflectValue)
-    return nil
+func DiffOperations(oldValue interface{}, newValue interface{}) ([]Operation, error) {
+    return defaultPatcher.DiffOperations(oldValue, newValue)
 }
 
-func helperCheckSettabilityAndSFDataType(structFieldValue reflect.Value) (structFieldDataType reflect.Type, err error) {
-    if !structFieldValue.CanSet() {
-        err = errors.New(fmt.Sprintf("CanSet() failed."))
-        return
-    }
-
-    structFieldDataType = structFieldValue.Type()
-
-    return
+func ThreeWayMerge(base interface{}, mine interface{}, theirs interface{}, resolver ConflictResolver) (interface{}, error) {
+    return defaultPatcher.ThreeWayMerge(base, mine, theirs, resolver)
 }
 
-func getNewReflectValueMapWithPayloadValues(structFieldType reflect.Type, iPayloadValue interface{}) (reflect.Value, error) {
-    newMap := reflect.MakeMap(structFieldType)
-    payloadMap, ok := iPayloadValue.(map[string]interface{})
-    if !ok {
-        return newMap, errors.New(fmt.Sprintf("Invalid payload data for %+v: incompatible for merging", structFieldType))
-    }
-
-    for k, v := range payloadMap {
-        mapItemReflectKey, mapKeyReflectVal := getMapKeyAndValueReflectType(structFieldType, k, v)
-        newMap.SetMapIndex(mapItemReflectKey, reflect.ValueOf(v).Convert(mapKeyReflectVal))
-    }
-    return newMap, nil
+func ThreeWayMergeDocuments(base []byte, mine []byte, theirs []byte, resolver ConflictResolver) ([]byte, error) {
+    return defaultPatcher.ThreeWayMergeDocuments(base, mine, theirs, resolver)
 }
 
-func getNewReflectValueSliceWithPayloadValues(structFieldValue reflect.Value, iPayloadValue interface{}) (sliceReflectValue reflect.Value, err error) {
-    if !structFieldValue.CanSet() {
-        err = errors.New(fmt.Sprintf("CanSet() failed."))
-        return
-    }
-
-    interfaceSlice, skip, err := parseStructValueToInterfaceArray(iPayloadValue)
-    if err != nil || skip {
-        return
-    }
-
-    // Don't support mutiple data type in array
-    err = checkMultipleDataTypeInPayloadArray(interfaceSlice)
-    if err != nil {
-        return
-    }
-
-    structFieldType := structFieldValue.Type()
-    if structFieldType.Kind() == reflect.Invalid {
-        err = errors.New(fmt.Sprintf("Invalid type! %+v", structFieldType))
-        return
-    }
-
-    sliceReflectValue = makeNewSlice(structFieldType, interfaceSlice)
-    k := structFieldType.Elem().Kind()
-    switch k {
-    case reflect.Struct:
-        for index, ival := range interfaceSlice {
-            nestedPayload, ok := ival.(map[string]interface{})
-            if !ok {
-                err = errors.New(fmt.Sprintf("Invalid payload data for %+v: incompatible for merging", structFieldType))
-                return
-            }
-
-            arrayItemAsStruct := reflect.Indirect(reflect.New(structFieldType.Elem()))
-            err = traverseStructAndMergeStructFieldsWithPayload(arrayItemAsStruct, nestedPayload)
-            if err != nil {
-                return
-            }
-            sliceReflectValue.Index(index).Set(arrayItemAsStruct)
-        }
-    case reflect.Map:
-        //TODO: need to consider array of map value is array. eg. [{"key": {key: ["value"]}}}
-        for index, ival := range interfaceSlice {
-            payloadMap, ok := ival.(map[string]interface{})
-            if !ok {
-                err = errors.New(fmt.Sprintf("Invalid payload data for %+v: incompatible for merging", structFieldType))
-                return
-            }
-            arrayItemType := structFieldValue.Type().Elem()
-            mapReflectVal, err := getNewReflectValueMapWithPayloadValues(arrayItemType, payloadMap)
-            if err != nil {
-                return sliceReflectValue, err
-            }
-            sliceReflectValue.Index(index).Set(mapReflectVal.Convert(structFieldType.Elem()))
-        }
-    case reflect.Interface:
-        for index, ival := range interfaceSlice {
-            nestedPayload, ok := ival.(interface{})
-            if !ok {
-                err = errors.New(fmt.Sprintf("Invalid payload data for %+v: incompatible for merging", structFieldType))
-                return
-            }
-            sliceReflectValue.Index(index).Set(reflect.ValueOf(nestedPayload).Convert(structFieldType.Elem()))
-        }
-    case reflect.Slice:
-        for index, ival := range interfaceSlice {
-            slicePayload, ok := ival.([]interface{})
-            if !ok {
-                err = errors.New(fmt.Sprintf("Invalid payload data for %+v: incompatible for merging", structFieldType))
-                return
-            }
-
-            arrayItemAsSlice := reflect.Indirect(reflect.New(structFieldType.Elem()))
-            // WARN: recursion below.
-            nestedSliceRefletValue, err := getNewReflectValueSliceWithPayloadValues(arrayItemAsSlice, slicePayload)
-            if err != nil {
-                return sliceReflectValue, err
-            }
-            sliceReflectValue.Index(index).Set(nestedSliceRefletValue)
-        }
-    case reflect.Bool, reflect.String:
-        for index, ival := range interfaceSlice {
-            if reflect.ValueOf(ival).Kind() != k {
-                err = errors.New(fmt.Sprintf("Invalid payload data for %+v: incompatible for merging", structFieldType))
-                return
-            }
-            sliceReflectValue.Index(index).Set(reflect.ValueOf(ival).Convert(structFieldType.Elem()))
-        }
-    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
-        reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
-        reflect.Float32, reflect.Float64:
-        for index, ival := range interfaceSlice {
-            if !isNumericValue(ival) {
-                err = errors.New(fmt.Sprintf("Invalid payload data for %+v: incompatible for merging", structFieldType))
-                return
-            }
-            sliceReflectValue.Index(index).Set(reflect.ValueOf(ival).Convert(structFieldType.Elem()))
-        }
-    }
-
-    return
+func Compose(patches ...[]byte) ([]byte, error) {
+    return defaultPatcher.Compose(patches...)
 }
 
-func isNumericValue(iPayloadValue interface{}) bool {
-    switch reflect.ValueOf(iPayloadValue).Kind() {
-    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
-        reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
-        reflect.Float32, reflect.Float64:
-        return true
-    default:
-        return false
-    }
-}
-
-// FIXME assume first one of json tag is json-key. Skip othe information from json-tag.
-func getJsonStructTag(structField reflect.StructField) (string, error) {
-    jsonTag := structField.Tag.Get("json")
-
-    tags := strings.Split(jsonTag, ",")
-    if len(tags) != 0 {
-        jsonTag = tags[0]
-    }
-
-    if jsonTag == "" {
-        return "", errors.New(fmt.Sprintf("Missing json tag in %+v struct field.", structField.Name))
-    }
-    return jsonTag, nil
-}
-
-func getMapKeyAndValueReflectType(structFieldType reflect.Type, k string, v interface{}) (mapKeyReflectValue reflect.Value, mapValueReflectType reflect.Type) {
-    mapValueReflectType = reflect.ValueOf(v).Type()
-    mapKeyReflectValue = reflect.ValueOf(k).Convert(structFieldType.Key())
-    return
+func ComposeTyped(targetType reflect.Type, patches ...[]byte) ([]byte, error) {
+    return defaultPatcher.ComposeTyped(targetType, patches...)
 }
 
-func makeNewSlice(sliceType reflect.Type, interfaces []interface{}) reflect.Value {
-    return reflect.MakeSlice(sliceType, len(interfaces), cap(interfaces))
+func FieldMaskFromPayload(src []byte, modelType reflect.Type) (FieldMask, error) {
+    return defaultPatcher.FieldMaskFromPayload(src, modelType)
 }
 
-func parseStructValueToInterfaceArray(val interface{}) ([]interface{}, bool, error) {
-    interfaceSlice, ok := val.([]interface{})
-    if !ok {
-        err := errors.New(fmt.Sprintf("Invalid payload data for %+v: incompatible for merging", interfaceSlice))
-        return interfaceSlice, false, err
-    }
-
-    if len(interfaceSlice) == 0 {
-        return interfaceSlice, true, nil
-    }
-    return interfaceSlice, false, nil
+func MergePatchFromFieldMask(mask FieldMask, src interface{}) ([]byte, error) {
+    return defaultPatcher.MergePatchFromFieldMask(mask, src)
 }
 
-func checkMultipleDataTypeInPayloadArray(interfaceSlice []interface{}) error {
-    var payloadArrayItemDataType reflect.Kind = reflect.Invalid
-    for _, ival := range interfaceSlice {
-        payloadArrayItemActualDataType := reflect.TypeOf(ival).Kind()
-        if payloadArrayItemDataType != reflect.Invalid {
-            if payloadArrayItemDataType != payloadArrayItemActualDataType {
-                err := errors.New(fmt.Sprintf("Unable to support multiple data type in Array or Slice.", ival))
-                return err
-            }
-        }
-        payloadArrayItemDataType = payloadArrayItemActualDataType
-    }
-
-    return nil
+func ApplyFieldMask(mask FieldMask, src interface{}, dst interface{}) error {
+    return defaultPatcher.ApplyFieldMask(mask, src, dst)
 }
-package main
 
-import (
-"encoding/json"
-"errors"
-"fmt"
-"reflect"
-"strings"
-)
-
-
-func PatchValues(src []byte, iStructPointer interface{}) error {
-    payloadMap := make(map[string]interface{})
-
-    err := json.Unmarshal(src, &payloadMap)
-    if err != nil {
-        return err
-    }
-
-    structReflectValue, err := getReflectValueFromIStructPointer(iStructPointer)
-    if err != nil {
-        return err
-    }
-
-    err = traverseStructAndMergeStructFieldsWithPayload(structReflectValue, payloadMap)
-    if err != nil {
-        return err
-    }
+func ToSQLUpdate(patch []byte, modelType reflect.Type, dialect Dialect) (string, []interface{}, error) {
+    return defaultPatcher.ToSQLUpdate(patch, modelType, dialect)
+}
 
-    return nil
+func ToMongoUpdate(patch []byte, modelType reflect.Type, pushPaths ...string) (map[string]interface{}, error) {
+    return defaultPatcher.ToMongoUpdate(patch, modelType, pushPaths...)
 }
 
 func getReflectValueFromIStructPointer(iStructPointer interface{}) (ret reflect.Value, err error) {
@@ -467,14 +94,19 @@ func getReflectValueFromIStructPointer(iStructPointer interface{}) (ret reflect.
     // Pointer is needed as a patch operation would require mutation.
     // A direct call to Elem results in panic, thus the if statement block below.
     if k := valueOfIStructPointer.Kind(); k != reflect.Ptr {
-        err = errors.New(fmt.Sprintf("%+v should be the pointer of struct.", typeOfIStructPointer))
+        err = errors.New(fmt.Sprintf("%+v should be the pointer of struct, slice or map.", typeOfIStructPointer))
+        return
+    }
+
+    if valueOfIStructPointer.IsNil() {
+        err = errors.New(fmt.Sprintf("%+v should not be nil.", typeOfIStructPointer))
         return
     }
 
     valueOfIStructPointerElem := valueOfIStructPointer.Elem()
 
-    if k := valueOfIStructPointerElem.Type().Kind(); k != reflect.Struct {
-        err = errors.New(fmt.Sprintf("%+v should be the struct type.", typeOfIStructPointer))
+    if k := valueOfIStructPointerElem.Type().Kind(); k != reflect.Struct && k != reflect.Slice && k != reflect.Map {
+        err = errors.New(fmt.Sprintf("%+v should be the struct, slice or map type.", typeOfIStructPointer))
         return
     }
 
@@ -489,36 +121,154 @@ func getReflectValueFromIStructPointer(iStructPointer interface{}) (ret reflect.
     return
 }
 
-func traverseStructAndMergeStructFieldsWithPayload(structReflectValue reflect.Value, payloadMap map[string]interface{}) error {
+func (session *patchSession) traverseStructAndMergeStructFieldsWithPayload(structReflectValue reflect.Value, payloadMap map[string]interface{}, path string) error {
+    err := session.checkDepth(path)
+    if err != nil {
+        return err
+    }
+
+    matchedPayloadKeys := make(map[string]bool, len(payloadMap))
     for index := 0; index < structReflectValue.NumField(); index += 1 {
         structField := structReflectValue.Type().Field(index)
-        structFieldJsonTag, err := getJsonStructTag(structField)
+        structFieldJsonTag, err := session.getJsonStructTag(structField)
         if err != nil {
             return err
         }
+        if structFieldJsonTag == "" {
+            continue
+        }
 
         if iPayloadValue, ok := payloadMap[structFieldJsonTag]; ok {
+            matchedPayloadKeys[structFieldJsonTag] = true
+            structFieldPath := appendJsonPointer(path, structFieldJsonTag)
+
+            fieldTag := parsePatchFieldTag(structField)
+            if fieldTag.version && path == "" && !session.copyVersion {
+                continue
+            }
+            if fieldTag.readOnly && !session.documentFromTarget {
+                if session.readOnlyPolicy == ReadOnlyRejected && !fieldTag.key {
+                    return &FieldError{Path: structFieldPath, Err: ErrReadOnlyField}
+                }
+                continue
+            }
+
             structFieldValue := structReflectValue.Field(index)
-            err := mergePayloadToStructField(structFieldValue, iPayloadValue)
+            err := session.authorizeStructField(structFieldValue, iPayloadValue, structFieldPath, fieldTag)
+            if err != nil {
+                return err
+            }
+
+            err = session.mergePayloadToStructField(structFieldValue, iPayloadValue, structFieldPath, fieldTag)
             if err != nil {
                 return err
             }
         }
     }
+
+    if session.strict {
+        for payloadKey := range payloadMap {
+            if !matchedPayloadKeys[payloadKey] {
+                return &FieldError{Path: appendJsonPointer(path, payloadKey), Err: ErrUnknownField}
+            }
+        }
+    }
+    return nil
+}
+
+func (session *patchSession) authorizeStructField(structFieldValue reflect.Value, iPayloadValue interface{}, path string, fieldTag patchFieldTag) error {
+    if len(fieldTag.permissions) != 0 && !session.documentFromTarget && !hasAnyRole(RolesFromContext(session.ctx), fieldTag.permissions) {
+        return &FieldError{Path: path, Err: ErrForbidden}
+    }
+
+    if session.authorizer == nil {
+        return nil
+    }
+
+    var oldValue interface{}
+    if structFieldValue.CanInterface() {
+        oldValue = structFieldValue.Interface()
+    }
+
+    err := session.authorizer.Authorize(session.ctx, path, oldValue, iPayloadValue)
+    if err != nil {
+        return &FieldError{Path: path, Err: err}
+    }
     return nil
 }
 
-func mergePayloadToStructField(structFieldValue reflect.Value, iPayloadValue interface{}) (err error) {
+func (session *patchSession) mergePayloadToStructField(structFieldValue reflect.Value, iPayloadValue interface{}, path string, fieldTag patchFieldTag) (err error) {
+
+    structFieldDataType := structFieldValue.Kind()
+
+    if iPayloadValue == nil && !isOptionalType(structFieldValue.Type()) {
+        switch session.nullPolicy {
+        case NullIgnored:
+            return nil
+        case NullRejected:
+            err = &FieldError{Path: path, Err: ErrNullRejected}
+            return
+        }
+    }
+
+    converter, err := session.resolveConverter(structFieldValue.Type(), fieldTag)
+    if err != nil {
+        return wrapFieldError(path, err)
+    }
+
+    if isTraversableStructType(structFieldValue.Type()) && converter == nil {
+        return wrapFieldError(path, session.mergePayloadToStructSF(structFieldValue, iPayloadValue, path))
+    }
+
+    if structFieldDataType == reflect.Ptr && converter == nil && iPayloadValue != nil {
+        elemConverter, err := session.resolveConverter(structFieldValue.Type().Elem(), fieldTag)
+        if err != nil {
+            return wrapFieldError(path, err)
+        }
+        if elemConverter == nil && isTraversableStructType(structFieldValue.Type().Elem()) {
+            return wrapFieldError(path, session.mergePayloadToStructPtrSF(structFieldValue, iPayloadValue, path))
+        }
+    }
+
+    // Everything below a struct is a leaf of the change set: maps and slices are replaced as a whole.
+    var oldValue interface{}
+    if structFieldValue.CanInterface() {
+        oldValue = structFieldValue.Interface()
+    }
+
+    if converter != nil {
+        err = mergePayloadWithConverterSF(structFieldValue, iPayloadValue, converter)
+    } else {
+        err = session.mergePayloadToLeafSF(structFieldValue, iPayloadValue, path, fieldTag)
+    }
+    if err != nil {
+        return wrapFieldError(path, err)
+    }
+
+    session.changes.record(path, oldValue, structFieldValue.Interface())
+    return
+}
+
+func (session *patchSession) mergePayloadToLeafSF(structFieldValue reflect.Value, iPayloadValue interface{}, path string, fieldTag patchFieldTag) (err error) {
+    if isOptionalType(structFieldValue.Type()) {
+        return session.mergePayloadToOptionalSF(structFieldValue, iPayloadValue, path, fieldTag)
+    }
+    if _, ok := sqlNullValueFieldIndex(structFieldValue.Type()); ok {
+        return session.mergePayloadToSqlNullSF(structFieldValue, iPayloadValue, path, fieldTag)
+    }
+    if implementsScanner(structFieldValue.Type()) {
+        return mergePayloadToScannerSF(structFieldValue, iPayloadValue)
+    }
 
     structFieldDataType := structFieldValue.Kind()
 
     switch structFieldDataType {
-    case reflect.Struct:
-        return mergePayloadToStructSF(structFieldValue, iPayloadValue)
     case reflect.Map:
-        return mergePayloadToMapSF(structFieldValue, iPayloadValue)
+        return session.mergePayloadToMapSF(structFieldValue, iPayloadValue, path, fieldTag)
+    case reflect.Ptr:
+        return session.mergePayloadToPtrSF(structFieldValue, iPayloadValue, path, fieldTag)
     case reflect.Slice:
-        return mergePayloadToSliceSF(structFieldValue, iPayloadValue)
+        return session.mergePayloadToSliceSF(structFieldValue, iPayloadValue, path, fieldTag)
     case reflect.Interface:
         return mergePayloadToInterfaceSF(structFieldValue, iPayloadValue)
     case reflect.Bool:
@@ -530,12 +280,12 @@ func mergePayloadToStructField(structFieldValue reflect.Value, iPayloadValue int
         reflect.Float32, reflect.Float64:
         return mergePayloadToNumberSF(structFieldValue, iPayloadValue)
     }
-    err = errors.New(fmt.Sprintf("Unsupported type %+v.", structFieldDataType))
+    err = fmt.Errorf("Unsupported type %+v: %w", structFieldDataType, ErrUnsupportedType)
     return
 }
 
 // TODO:: need to fix for null struct
-func mergePayloadToStructSF(structFieldValue reflect.Value, iPayloadValue interface{}) error {
+func (session *patchSession) mergePayloadToStructSF(structFieldValue reflect.Value, iPayloadValue interface{}, path string) error {
     structFieldDataType, err := helperCheckSettabilityAndSFDataType(structFieldValue)
     if err != nil {
         return err
@@ -543,17 +293,54 @@ func mergePayloadToStructSF(structFieldValue reflect.Value, iPayloadValue interf
 
     payloadMap, ok := iPayloadValue.(map[string]interface{})
     if !ok {
-        return errors.New(fmt.Sprintf("Invalid payload data for %+v: incompatible for merging.", structFieldDataType))
+        return fmt.Errorf("Invalid payload data for %+v: %w.", structFieldDataType, ErrTypeMismatch)
+    }
+
+    err = session.traverseStructAndMergeStructFieldsWithPayload(structFieldValue, payloadMap, path)
+    if err != nil {
+        return err
+    }
+    return nil
+}
+
+// mergePayloadToStructPtrSF allocates a nil pointer and merges the payload into the struct it points to.
+func (session *patchSession) mergePayloadToStructPtrSF(structFieldValue reflect.Value, iPayloadValue interface{}, path string) error {
+    structFieldDataType, err := helperCheckSettabilityAndSFDataType(structFieldValue)
+    if err != nil {
+        return err
+    }
+
+    if structFieldValue.IsNil() {
+        structFieldValue.Set(reflect.New(structFieldDataType.Elem()))
+        session.changes.recordAllocation(path)
+    }
+    return session.mergePayloadToStructSF(structFieldValue.Elem(), iPayloadValue, path)
+}
+
+// mergePayloadToPtrSF replaces a pointer to a leaf value, null sets it to nil.
+func (session *patchSession) mergePayloadToPtrSF(structFieldValue reflect.Value, iPayloadValue interface{}, path string, fieldTag patchFieldTag) error {
+    structFieldDataType, err := helperCheckSettabilityAndSFDataType(structFieldValue)
+    if err != nil {
+        return err
+    }
+
+    if iPayloadValue == nil {
+        structFieldValue.Set(reflect.Zero(structFieldDataType))
+        return nil
     }
 
-    err = traverseStructAndMergeStructFieldsWithPayload(structFieldValue, payloadMap)
+    elemReflectValue, err := session.newReflectValueFromPayload(structFieldDataType.Elem(), iPayloadValue, path, fieldTag)
     if err != nil {
         return err
     }
+
+    pointerReflectValue := reflect.New(structFieldDataType.Elem())
+    pointerReflectValue.Elem().Set(elemReflectValue)
+    structFieldValue.Set(pointerReflectValue)
     return nil
 }
 
-func mergePayloadToMapSF(structFieldValue reflect.Value, iPayloadValue interface{}) error {
+func (session *patchSession) mergePayloadToMapSF(structFieldValue reflect.Value, iPayloadValue interface{}, path string, fieldTag patchFieldTag) error {
     structFieldDataType, err := helperCheckSettabilityAndSFDataType(structFieldValue)
     if err != nil {
         return err
@@ -566,10 +353,14 @@ func mergePayloadToMapSF(structFieldValue reflect.Value, iPayloadValue interface
         mapReflectValue = reflect.MakeMap(structFieldType)
     } else {
         if payloadKind := reflect.ValueOf(iPayloadValue).Kind(); payloadKind != reflect.Map {
-            return errors.New(fmt.Sprintf("Invalid payload data for %+v: incompatible for merging", structFieldDataType))
+            return fmt.Errorf("Invalid payload data for %+v: %w", structFieldDataType, ErrTypeMismatch)
         }
 
-        mapReflectValue, err = getNewReflectValueMapWithPayloadValues(structFieldValue.Type(), iPayloadValue)
+        mapReflectValue, err = session.getNewReflectValueMapWithPayloadValues(structFieldValue.Type(), iPayloadValue, path, fieldTag)
+        if err != nil {
+            return err
+        }
+        err = session.restoreProtectedFields(mapReflectValue, structFieldValue, iPayloadValue, path)
         if err != nil {
             return err
         }
@@ -580,11 +371,15 @@ func mergePayloadToMapSF(structFieldValue reflect.Value, iPayloadValue interface
 }
 
 func mergePayloadToInterfaceSF(structFieldValue reflect.Value, iPayloadValue interface{}) error {
-    _, err := helperCheckSettabilityAndSFDataType(structFieldValue)
+    structFieldDataType, err := helperCheckSettabilityAndSFDataType(structFieldValue)
     if err != nil {
         return err
     }
 
+    if iPayloadValue == nil {
+        structFieldValue.Set(reflect.Zero(structFieldDataType))
+        return nil
+    }
     structFieldValue.Set(reflect.ValueOf(iPayloadValue))
     return nil
 }
@@ -599,7 +394,7 @@ func mergePayloadToBoolSF(structFieldValue reflect.Value, iPayloadValue interfac
         structFieldValue.SetBool(false)
     } else {
         if reflect.ValueOf(iPayloadValue).Kind() != reflect.Bool {
-            return errors.New(fmt.Sprintf("Invalid payload data for %+v: incompatible for merging", structFieldDataType))
+            return fmt.Errorf("Invalid payload data for %+v: %w", structFieldDataType, ErrTypeMismatch)
         }
         structFieldValue.Set(reflect.ValueOf(iPayloadValue).Convert(structFieldValue.Type()))
     }
@@ -617,7 +412,7 @@ func mergePayloadToStringSF(structFieldValue reflect.Value, iPayloadValue interf
         structFieldValue.SetString("")
     } else {
         if reflect.ValueOf(iPayloadValue).Kind() != reflect.String {
-            return errors.New(fmt.Sprintf("Invalid payload data for %+v: incompatible for merging", structFieldDataType))
+            return fmt.Errorf("Invalid payload data for %+v: %w", structFieldDataType, ErrTypeMismatch)
         }
         structFieldValue.Set(reflect.ValueOf(iPayloadValue).Convert(structFieldValue.Type()))
     }
@@ -635,7 +430,7 @@ func mergePayloadToNumberSF(structFieldValue reflect.Value, iPayloadValue interf
         structFieldValue.Set(reflect.ValueOf(0).Convert(structFieldValue.Type()))
     } else {
         if !isNumericValue(iPayloadValue) {
-            return errors.New(fmt.Sprintf("Invalid payload data for %+v: incompatible for merging", structFieldDataType))
+            return fmt.Errorf("Invalid payload data for %+v: %w", structFieldDataType, ErrTypeMismatch)
         }
         structFieldValue.Set(reflect.ValueOf(iPayloadValue).Convert(structFieldValue.Type()))
     }
@@ -643,7 +438,7 @@ func mergePayloadToNumberSF(structFieldValue reflect.Value, iPayloadValue interf
     return nil
 }
 
-func mergePayloadToSliceSF(structFieldValue reflect.Value, iPayloadValue interface{}) error {
+func (session *patchSession) mergePayloadToSliceSF(structFieldValue reflect.Value, iPayloadValue interface{}, path string, fieldTag patchFieldTag) error {
     structFieldDataType, err := helperCheckSettabilityAndSFDataType(structFieldValue)
     if err != nil {
         return err
@@ -654,10 +449,15 @@ func mergePayloadToSliceSF(structFieldValue reflect.Value, iPayloadValue interfa
         sliceReflectValue = makeNewSlice(structFieldDataType, emptyInterfaceSlice)
     }else{
         if payloadKind := reflect.ValueOf(iPayloadValue).Kind(); payloadKind != reflect.Slice {
-            return errors.New(fmt.Sprintf("Invalid payload data for %+v: incompatible for merging", structFieldDataType))
+            return fmt.Errorf("Invalid payload data for %+v: %w", structFieldDataType, ErrTypeMismatch)
+        }
+
+        sliceReflectValue, err = session.getNewReflectValueSliceWithPayloadValues(structFieldValue, iPayloadValue, path, fieldTag)
+        if err != nil {
+            return err
         }
 
-        sliceReflectValue, err = getNewReflectValueSliceWithPayloadValues(structFieldValue, iPayloadValue)
+        err = session.restoreProtectedFields(sliceReflectValue, structFieldValue, iPayloadValue, path)
         if err != nil {
             return err
         }
@@ -666,9 +466,214 @@ func mergePayloadToSliceSF(structFieldValue reflect.Value, iPayloadValue interfa
     return nil
 }
 
+// isProtectedField tells whether the caller may not write a field: it is read-only or the context lacks its roles.
+func (session *patchSession) isProtectedField(fieldTag patchFieldTag) bool {
+    return fieldTag.readOnly || (len(fieldTag.permissions) != 0 && !hasAnyRole(RolesFromContext(session.ctx), fieldTag.permissions))
+}
+
+// protectedFieldsError returns ErrReadOnlyField or ErrForbidden when values of targetType hold protected fields.
+func (session *patchSession) protectedFieldsError(targetType reflect.Type, visitedTypes map[reflect.Type]bool) error {
+    switch targetType.Kind() {
+    case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+        return session.protectedFieldsError(targetType.Elem(), visitedTypes)
+    case reflect.Struct:
+        if !isTraversableStructType(targetType) || visitedTypes[targetType] {
+            return nil
+        }
+        visitedTypes[targetType] = true
+
+        var protectedErr error
+        for index := 0; index < targetType.NumField(); index += 1 {
+            structField := targetType.Field(index)
+            if structField.PkgPath != "" {
+                continue
+            }
+            fieldTag := parsePatchFieldTag(structField)
+            if fieldTag.readOnly {
+                return ErrReadOnlyField
+            }
+            if session.isProtectedField(fieldTag) {
+                protectedErr = ErrForbidden
+                continue
+            }
+            if err := session.protectedFieldsError(structField.Type, visitedTypes); err != nil {
+                if err == ErrReadOnlyField {
+                    return err
+                }
+                protectedErr = err
+            }
+        }
+        return protectedErr
+    }
+    return nil
+}
+
+// restoreProtectedFields gives the protected fields of a value rebuilt from the payload the value they held before.
+// Map values are matched by key and slice items by their `patch:"key"` field. Slice items without a key field are
+// matched by index, so adding or removing them would hand the values of one item to another and fails instead.
+// New items keep the zero value.
+func (session *patchSession) restoreProtectedFields(rebuiltReflectValue reflect.Value, originalReflectValue reflect.Value, iPayloadValue interface{}, path string) error {
+    if session.documentFromTarget {
+        return nil
+    }
+
+    switch rebuiltReflectValue.Kind() {
+    case reflect.Ptr:
+        if rebuiltReflectValue.IsNil() || originalReflectValue.IsNil() {
+            return nil
+        }
+        return session.restoreProtectedFields(rebuiltReflectValue.Elem(), originalReflectValue.Elem(), iPayloadValue, path)
+    case reflect.Struct:
+        if !isTraversableStructType(rebuiltReflectValue.Type()) {
+            return nil
+        }
+        payloadMap, _ := iPayloadValue.(map[string]interface{})
+        for index := 0; index < rebuiltReflectValue.NumField(); index += 1 {
+            structField := rebuiltReflectValue.Type().Field(index)
+            structFieldValue := rebuiltReflectValue.Field(index)
+            if !structFieldValue.CanSet() {
+                continue
+            }
+            if session.isProtectedField(parsePatchFieldTag(structField)) {
+                structFieldValue.Set(cloneReflectValue(originalReflectValue.Field(index)))
+                continue
+            }
+
+            structFieldJsonTag, _ := session.getJsonStructTag(structField)
+            err := session.restoreProtectedFields(structFieldValue, originalReflectValue.Field(index), payloadMap[structFieldJsonTag], appendJsonPointer(path, structFieldJsonTag))
+            if err != nil {
+                return err
+            }
+        }
+    case reflect.Slice, reflect.Array:
+        return session.restoreProtectedItems(rebuiltReflectValue, originalReflectValue, iPayloadValue, path)
+    case reflect.Map:
+        payloadMap, _ := iPayloadValue.(map[string]interface{})
+        iterator := rebuiltReflectValue.MapRange()
+        for iterator.Next() {
+            originalEntry := originalReflectValue.MapIndex(iterator.Key())
+            if !originalEntry.IsValid() {
+                continue
+            }
+            key := iterator.Key().String()
+            entryReflectValue := reflect.New(rebuiltReflectValue.Type().Elem()).Elem()
+            entryReflectValue.Set(iterator.Value())
+            err := session.restoreProtectedFields(entryReflectValue, originalEntry, payloadMap[key], appendJsonPointer(path, key))
+            if err != nil {
+                return err
+            }
+            rebuiltReflectValue.SetMapIndex(iterator.Key(), entryReflectValue)
+        }
+    }
+    return nil
+}
+
+// restoreProtectedItems pairs each rebuilt slice item with the original item it stands for.
+func (session *patchSession) restoreProtectedItems(rebuiltReflectValue reflect.Value, originalReflectValue reflect.Value, iPayloadValue interface{}, path string) error {
+    itemType := rebuiltReflectValue.Type().Elem()
+    protectedErr := session.protectedFieldsError(itemType, make(map[reflect.Type]bool))
+    if protectedErr == nil || rebuiltReflectValue.Len() == 0 || originalReflectValue.Len() == 0 {
+        return nil
+    }
+
+    payloadItems, _ := iPayloadValue.([]interface{})
+    payloadItem := func(index int) interface{} {
+        if index < len(payloadItems) {
+            return payloadItems[index]
+        }
+        return nil
+    }
+
+    keyFieldIndex, keyJsonTag, ok, err := session.lookupItemKeyField(itemType)
+    if err != nil {
+        return &FieldError{Path: path, Err: err}
+    }
+    if !ok {
+        if rebuiltReflectValue.Len() != originalReflectValue.Len() {
+            return &FieldError{Path: path, Err: fmt.Errorf("Unable to add or remove items holding protected fields without a key field: %w", protectedErr)}
+        }
+        for index := 0; index < rebuiltReflectValue.Len(); index += 1 {
+            err := session.restoreProtectedFields(rebuiltReflectValue.Index(index), originalReflectValue.Index(index), payloadItem(index), appendJsonPointerIndex(path, index))
+            if err != nil {
+                return err
+            }
+        }
+        return nil
+    }
+
+    matchedIndexes := make(map[int]bool)
+    for index := 0; index < rebuiltReflectValue.Len(); index += 1 {
+        itemPath := appendJsonPointerIndex(path, index)
+        payloadItemMap, _ := payloadItem(index).(map[string]interface{})
+        payloadKey, ok := payloadItemMap[keyJsonTag]
+        if !ok || payloadKey == nil {
+            continue
+        }
+
+        originalIndex, err := findItemByKey(originalReflectValue, keyFieldIndex, payloadKey, itemPath)
+        if err != nil {
+            return err
+        }
+        if originalIndex < 0 {
+            continue
+        }
+        if matchedIndexes[originalIndex] {
+            return &FieldError{Path: appendJsonPointer(itemPath, keyJsonTag), Err: fmt.Errorf("Duplicate item key %v: %w", payloadKey, protectedErr)}
+        }
+        matchedIndexes[originalIndex] = true
+
+        err = session.restoreProtectedFields(rebuiltReflectValue.Index(index), originalReflectValue.Index(originalIndex), payloadItemMap, itemPath)
+        if err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// lookupItemKeyField returns the field of a struct item type tagged `patch:"key"`, if any.
+func (session *patchSession) lookupItemKeyField(itemType reflect.Type) (int, string, bool, error) {
+    for itemType.Kind() == reflect.Ptr {
+        itemType = itemType.Elem()
+    }
+    if !isTraversableStructType(itemType) {
+        return 0, "", false, nil
+    }
+
+    for index := 0; index < itemType.NumField(); index += 1 {
+        structField := itemType.Field(index)
+        if !parsePatchFieldTag(structField).key {
+            continue
+        }
+        structFieldJsonTag, err := session.getJsonStructTag(structField)
+        if err != nil {
+            return 0, "", false, err
+        }
+        return index, structFieldJsonTag, true, nil
+    }
+    return 0, "", false, nil
+}
+
+// findItemByKey returns the index of the item whose key field has the JSON value payloadKey, or -1.
+func findItemByKey(itemsReflectValue reflect.Value, keyFieldIndex int, payloadKey interface{}, path string) (int, error) {
+    for index := 0; index < itemsReflectValue.Len(); index += 1 {
+        itemReflectValue := reflect.Indirect(itemsReflectValue.Index(index))
+        if !itemReflectValue.IsValid() {
+            continue
+        }
+        itemKey, err := leafToDocument(itemReflectValue.Field(keyFieldIndex).Interface(), path)
+        if err != nil {
+            return -1, err
+        }
+        if reflect.DeepEqual(itemKey, plainDocument(payloadKey)) {
+            return index, nil
+        }
+    }
+    return -1, nil
+}
+
 func helperCheckSettabilityAndSFDataType(structFieldValue reflect.Value) (structFieldDataType reflect.Type, err error) {
     if !structFieldValue.CanSet() {
-        err = errors.New(fmt.Sprintf("CanSet() failed."))
+        err = fmt.Errorf("Unable to set the value of %+v: %w", structFieldValue.Type(), ErrUnsupportedType)
         return
     }
 
@@ -677,56 +682,174 @@ func helperCheckSettabilityAndSFDataType(structFieldValue reflect.Value) (struct
     return
 }
 
-func getNewReflectValueMapWithPayloadValues(structFieldType reflect.Type, iPayloadValue interface{}) (reflect.Value, error) {
+func (session *patchSession) getNewReflectValueMapWithPayloadValues(structFieldType reflect.Type, iPayloadValue interface{}, path string, fieldTag patchFieldTag) (reflect.Value, error) {
     newMap := reflect.MakeMap(structFieldType)
     payloadMap, ok := iPayloadValue.(map[string]interface{})
     if !ok {
-        return newMap, errors.New(fmt.Sprintf("Invalid payload data for %+v: incompatible for merging", structFieldType))
+        return newMap, fmt.Errorf("Invalid payload data for %+v: %w", structFieldType, ErrTypeMismatch)
+    }
+
+    err := session.checkDepth(path)
+    if err != nil {
+        return newMap, err
     }
 
     for k, v := range payloadMap {
-        mapItemReflectKey, mapKeyReflectVal := getMapKeyAndValueReflectType(structFieldType, k, v)
-        newMap.SetMapIndex(mapItemReflectKey, reflect.ValueOf(v).Convert(mapKeyReflectVal))
+        mapItemReflectKey := reflect.ValueOf(k).Convert(structFieldType.Key())
+        mapItemReflectValue, err := session.newReflectValueFromPayload(structFieldType.Elem(), v, appendJsonPointer(path, k), fieldTag.itemTag())
+        if err != nil {
+            return newMap, err
+        }
+        newMap.SetMapIndex(mapItemReflectKey, mapItemReflectValue)
     }
     return newMap, nil
 }
 
-func getNewReflectValueSliceWithPayloadValues(structFieldValue reflect.Value, iPayloadValue interface{}) (sliceReflectValue reflect.Value, err error) {
-    if !structFieldValue.CanSet() {
-        err = errors.New(fmt.Sprintf("CanSet() failed."))
-        return
+// mergePayloadToMapEntries merges the payload into a map key by key, used for maps patched as the top-level target.
+// Existing struct values are merged, other values are replaced and null deletes the key.
+func (session *patchSession) mergePayloadToMapEntries(mapReflectValue reflect.Value, iPayloadValue interface{}, path string) error {
+    mapType := mapReflectValue.Type()
+    if mapType.Key().Kind() != reflect.String {
+        return &FieldError{Path: path, Err: fmt.Errorf("Unsupported map key type %+v: %w", mapType.Key(), ErrUnsupportedType)}
     }
 
-    interfaceSlice, skip, err := parseStructValueToInterfaceArray(iPayloadValue)
-    if err != nil || skip {
+    payloadMap, ok := iPayloadValue.(map[string]interface{})
+    if !ok {
+        return &FieldError{Path: path, Err: fmt.Errorf("Invalid payload data for %+v: %w", mapType, ErrTypeMismatch)}
+    }
+
+    if mapReflectValue.IsNil() {
+        mapReflectValue.Set(reflect.MakeMap(mapType))
+    }
+
+    for key, payloadValue := range payloadMap {
+        entryPath := appendJsonPointer(path, key)
+        mapKey := reflect.ValueOf(key).Convert(mapType.Key())
+        existingEntry := mapReflectValue.MapIndex(mapKey)
+
+        if payloadValue == nil && !isOptionalType(mapType.Elem()) {
+            switch session.nullPolicy {
+            case NullIgnored:
+            case NullRejected:
+                return &FieldError{Path: entryPath, Err: ErrNullRejected}
+            default:
+                if existingEntry.IsValid() {
+                    session.changes.record(entryPath, existingEntry.Interface(), nil)
+                    mapReflectValue.SetMapIndex(mapKey, reflect.Value{})
+                }
+            }
+            continue
+        }
+
+        if !existingEntry.IsValid() {
+            entryReflectValue, err := session.newReflectValueFromPayload(mapType.Elem(), payloadValue, entryPath, patchFieldTag{})
+            if err != nil {
+                return err
+            }
+            session.changes.record(entryPath, nil, entryReflectValue.Interface())
+            mapReflectValue.SetMapIndex(mapKey, entryReflectValue)
+            continue
+        }
+
+        entryReflectValue := reflect.New(mapType.Elem()).Elem()
+        entryReflectValue.Set(existingEntry)
+        err := session.mergePayloadToStructField(entryReflectValue, payloadValue, entryPath, patchFieldTag{})
+        if err != nil {
+            return err
+        }
+        mapReflectValue.SetMapIndex(mapKey, entryReflectValue)
+    }
+    return nil
+}
+
+// newReflectValueFromPayload builds a value of targetType from the payload with the rules of a struct field.
+// The new value replaces a leaf as a whole, so its content is not recorded in the change set.
+func (session *patchSession) newReflectValueFromPayload(targetType reflect.Type, iPayloadValue interface{}, path string, fieldTag patchFieldTag) (reflect.Value, error) {
+    newReflectValue := reflect.New(targetType).Elem()
+
+    detachedSession := *session
+    detachedSession.changes = nil
+    err := detachedSession.mergePayloadToStructField(newReflectValue, iPayloadValue, path, fieldTag)
+    return newReflectValue, err
+}
+
+func (session *patchSession) getNewReflectValueSliceWithPayloadValues(structFieldValue reflect.Value, iPayloadValue interface{}, path string, fieldTag patchFieldTag) (sliceReflectValue reflect.Value, err error) {
+    if !structFieldValue.CanSet() {
+        err = fmt.Errorf("Unable to set the value of %+v: %w", structFieldValue.Type(), ErrUnsupportedType)
         return
     }
 
-    // Don't support mutiple data type in array
-    err = checkMultipleDataTypeInPayloadArray(interfaceSlice)
+    err = session.checkDepth(path)
     if err != nil {
         return
     }
 
     structFieldType := structFieldValue.Type()
     if structFieldType.Kind() == reflect.Invalid {
-        err = errors.New(fmt.Sprintf("Invalid type! %+v", structFieldType))
+        err = fmt.Errorf("Invalid type %+v: %w", structFieldType, ErrUnsupportedType)
+        return
+    }
+
+    interfaceSlice, skip, err := parseStructValueToInterfaceArray(iPayloadValue)
+    if err != nil {
+        return
+    }
+    if skip {
+        sliceReflectValue = makeNewSlice(structFieldType, interfaceSlice)
+        return
+    }
+
+    converter, err := session.resolveConverter(structFieldType.Elem(), fieldTag.itemTag())
+    if err != nil {
         return
     }
 
+    // Don't support mutiple data type in array
+    if !session.allowMixedArrays && converter == nil {
+        err = checkMultipleDataTypeInPayloadArray(interfaceSlice)
+        if err != nil {
+            return
+        }
+    }
+
     sliceReflectValue = makeNewSlice(structFieldType, interfaceSlice)
+
+    if converter != nil {
+        for index, ival := range interfaceSlice {
+            arrayItemReflectValue, err := convertPayloadWithConverter(structFieldType.Elem(), ival, converter)
+            if err != nil {
+                return sliceReflectValue, wrapFieldError(appendJsonPointerIndex(path, index), err)
+            }
+            sliceReflectValue.Index(index).Set(arrayItemReflectValue)
+        }
+        return
+    }
+
     k := structFieldType.Elem().Kind()
+
+    // Pointers and structs patched as a single value, e.g. sql.NullString, are built like a struct field.
+    if k == reflect.Ptr || (k == reflect.Struct && !isTraversableStructType(structFieldType.Elem())) {
+        for index, ival := range interfaceSlice {
+            arrayItemReflectValue, err := session.newReflectValueFromPayload(structFieldType.Elem(), ival, appendJsonPointerIndex(path, index), fieldTag.itemTag())
+            if err != nil {
+                return sliceReflectValue, err
+            }
+            sliceReflectValue.Index(index).Set(arrayItemReflectValue)
+        }
+        return
+    }
+
     switch k {
     case reflect.Struct:
-        arrayItemAsStruct := reflect.Indirect(reflect.New(structFieldType.Elem()))
         for index, ival := range interfaceSlice {
             nestedPayload, ok := ival.(map[string]interface{})
             if !ok {
-                err = errors.New(fmt.Sprintf("Invalid payload data for %+v: incompatible for merging", structFieldType))
+                err = fmt.Errorf("Invalid payload data for %+v: %w", structFieldType, ErrTypeMismatch)
                 return
             }
 
-            err = traverseStructAndMergeStructFieldsWithPayload(arrayItemAsStruct, nestedPayload)
+            arrayItemAsStruct := reflect.Indirect(reflect.New(structFieldType.Elem()))
+            err = session.traverseStructAndMergeStructFieldsWithPayload(arrayItemAsStruct, nestedPayload, appendJsonPointerIndex(path, index))
             if err != nil {
                 return
             }
@@ -737,11 +860,11 @@ func getNewReflectValueSliceWithPayloadValues(structFieldValue reflect.Value, iP
         for index, ival := range interfaceSlice {
             payloadMap, ok := ival.(map[string]interface{})
             if !ok {
-                err = errors.New(fmt.Sprintf("Invalid payload data for %+v: incompatible for merging", structFieldType))
+                err = fmt.Errorf("Invalid payload data for %+v: %w", structFieldType, ErrTypeMismatch)
                 return
             }
             arrayItemType := structFieldValue.Type().Elem()
-            mapReflectVal, err := getNewReflectValueMapWithPayloadValues(arrayItemType, payloadMap)
+            mapReflectVal, err := session.getNewReflectValueMapWithPayloadValues(arrayItemType, payloadMap, appendJsonPointerIndex(path, index), fieldTag.itemTag())
             if err != nil {
                 return sliceReflectValue, err
             }
@@ -751,22 +874,22 @@ func getNewReflectValueSliceWithPayloadValues(structFieldValue reflect.Value, iP
         for index, ival := range interfaceSlice {
             nestedPayload, ok := ival.(interface{})
             if !ok {
-                err = errors.New(fmt.Sprintf("Invalid payload data for %+v: incompatible for merging", structFieldType))
+                err = fmt.Errorf("Invalid payload data for %+v: %w", structFieldType, ErrTypeMismatch)
                 return
             }
             sliceReflectValue.Index(index).Set(reflect.ValueOf(nestedPayload).Convert(structFieldType.Elem()))
         }
     case reflect.Slice:
-        arrayItemAsSlice := reflect.Indirect(reflect.New(structFieldType.Elem()))
         for index, ival := range interfaceSlice {
             slicePayload, ok := ival.([]interface{})
             if !ok {
-                err = errors.New(fmt.Sprintf("Invalid payload data for %+v: incompatible for merging", structFieldType))
+                err = fmt.Errorf("Invalid payload data for %+v: %w", structFieldType, ErrTypeMismatch)
                 return
             }
 
+            arrayItemAsSlice := reflect.Indirect(reflect.New(structFieldType.Elem()))
             // WARN: recursion below.
-            nestedSliceRefletValue, err := getNewReflectValueSliceWithPayloadValues(arrayItemAsSlice, slicePayload)
+            nestedSliceRefletValue, err := session.getNewReflectValueSliceWithPayloadValues(arrayItemAsSlice, slicePayload, appendJsonPointerIndex(path, index), fieldTag)
             if err != nil {
                 return sliceReflectValue, err
             }
@@ -775,7 +898,7 @@ func getNewReflectValueSliceWithPayloadValues(structFieldValue reflect.Value, iP
     case reflect.Bool, reflect.String:
         for index, ival := range interfaceSlice {
             if reflect.ValueOf(ival).Kind() != k {
-                err = errors.New(fmt.Sprintf("Invalid payload data for %+v: incompatible for merging", structFieldType))
+                err = fmt.Errorf("Invalid payload data for %+v: %w", structFieldType, ErrTypeMismatch)
                 return
             }
             sliceReflectValue.Index(index).Set(reflect.ValueOf(ival).Convert(structFieldType.Elem()))
@@ -785,7 +908,7 @@ func getNewReflectValueSliceWithPayloadValues(structFieldValue reflect.Value, iP
         reflect.Float32, reflect.Float64:
         for index, ival := range interfaceSlice {
             if !isNumericValue(ival) {
-                err = errors.New(fmt.Sprintf("Invalid payload data for %+v: incompatible for merging", structFieldType))
+                err = fmt.Errorf("Invalid payload data for %+v: %w", structFieldType, ErrTypeMismatch)
                 return
             }
             sliceReflectValue.Index(index).Set(reflect.ValueOf(ival).Convert(structFieldType.Elem()))
@@ -806,9 +929,15 @@ func isNumericValue(iPayloadValue interface{}) bool {
     }
 }
 
+// isTraversableStructType tells plain structs, merged field by field, from structs patched as a single value.
+func isTraversableStructType(targetType reflect.Type) bool {
+    return targetType.Kind() == reflect.Struct && !implementsScanner(targetType) && !isOptionalType(targetType)
+}
+
 // FIXME assume first one of json tag is json-key. Skip othe information from json-tag.
-func getJsonStructTag(structField reflect.StructField) (string, error) {
-    jsonTag := structField.Tag.Get("json")
+// An empty key with a nil error means the field has no tag and should be skipped.
+func (session *patchSession) getJsonStructTag(structField reflect.StructField) (string, error) {
+    jsonTag := structField.Tag.Get(session.tagName)
 
     tags := strings.Split(jsonTag, ",")
     if len(tags) != 0 {
@@ -816,17 +945,14 @@ func getJsonStructTag(structField reflect.StructField) (string, error) {
     }
 
     if jsonTag == "" {
-        return "", errors.New(fmt.Sprintf("Missing json tag in %+v struct field.", structField.Name))
+        if session.skipUntagged {
+            return "", nil
+        }
+        return "", errors.New(fmt.Sprintf("Missing %s tag in %+v struct field.", session.tagName, structField.Name))
     }
     return jsonTag, nil
 }
 
-func getMapKeyAndValueReflectType(structFieldType reflect.Type, k string, v interface{}) (mapKeyReflectValue reflect.Value, mapValueReflectType reflect.Type) {
-    mapValueReflectType = reflect.ValueOf(v).Type()
-    mapKeyReflectValue = reflect.ValueOf(k).Convert(structFieldType.Key())
-    return
-}
-
 func makeNewSlice(sliceType reflect.Type, interfaces []interface{}) reflect.Value {
     return reflect.MakeSlice(sliceType, len(interfaces), cap(interfaces))
 }
@@ -834,7 +960,7 @@ func makeNewSlice(sliceType reflect.Type, interfaces []interface{}) reflect.Valu
 func parseStructValueToInterfaceArray(val interface{}) ([]interface{}, bool, error) {
     interfaceSlice, ok := val.([]interface{})
     if !ok {
-        err := errors.New(fmt.Sprintf("Invalid payload data for %+v: incompatible for merging", interfaceSlice))
+        err := fmt.Errorf("Invalid payload data for %+v: %w", interfaceSlice, ErrTypeMismatch)
         return interfaceSlice, false, err
     }
 
@@ -847,11 +973,14 @@ func parseStructValueToInterfaceArray(val interface{}) ([]interface{}, bool, err
 func checkMultipleDataTypeInPayloadArray(interfaceSlice []interface{}) error {
     var payloadArrayItemDataType reflect.Kind = reflect.Invalid
     for _, ival := range interfaceSlice {
+        // null fits any item type which accepts it, e.g. pointers.
+        if ival == nil {
+            continue
+        }
         payloadArrayItemActualDataType := reflect.TypeOf(ival).Kind()
         if payloadArrayItemDataType != reflect.Invalid {
             if payloadArrayItemDataType != payloadArrayItemActualDataType {
-                err := errors.New(fmt.Sprintf("Unable to support multiple data type in Array or Slice.", ival))
-                return err
+                return fmt.Errorf("Unable to support multiple data type in Array or Slice: %w", ErrTypeMismatch)
             }
         }
         payloadArrayItemDataType = payloadArrayItemActualDataType
@@ -859,4 +988,3 @@ func checkMultipleDataTypeInPayloadArray(interfaceSlice []interface{}) error {
 
     return nil
 }
-
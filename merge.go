@@ -0,0 +1,173 @@
+package main
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "reflect"
+)
+
+// Conflict is a path both sides changed differently since base. An absent or deleted value is nil.
+type Conflict struct {
+    Path   string      `json:"path"`
+    Base   interface{} `json:"base"`
+    Mine   interface{} `json:"mine"`
+    Theirs interface{} `json:"theirs"`
+}
+
+// ConflictResolver returns the merged value of a conflicting path, nil deletes it. Returning an error aborts the merge.
+type ConflictResolver func(conflict Conflict) (interface{}, error)
+
+func PreferMine(conflict Conflict) (interface{}, error) {
+    return conflict.Mine, nil
+}
+
+func PreferTheirs(conflict Conflict) (interface{}, error) {
+    return conflict.Theirs, nil
+}
+
+// ThreeWayMerge merges the changes mine and theirs made to base, field by field through the Patcher tag.
+// It returns a pointer to a merged copy of base and leaves the three values untouched. Maps and slices held
+// by a field are compared whole. Without a resolver, conflicting fields fail with a MergeConflictError.
+// A `patch:"version"` field is left out of the merge and incremented from base when the merge changed something.
+func (patcher *Patcher) ThreeWayMerge(base interface{}, mine interface{}, theirs interface{}, resolver ConflictResolver) (interface{}, error) {
+    baseReflectValue := reflect.Indirect(reflect.ValueOf(base))
+    if !baseReflectValue.IsValid() {
+        return nil, errors.New("Base should not be nil.")
+    }
+
+    session := patcher.newSession(context.Background())
+    baseDocument, err := session.toDocument(baseReflectValue, "")
+    if err != nil {
+        return nil, err
+    }
+    mineDocument, theirsDocument, err := session.toDocuments(mine, theirs)
+    if err != nil {
+        return nil, err
+    }
+
+    // The merged copy gets its own version from base, the versions of both sides are neither merged nor checked.
+    documents := []*interface{}{&baseDocument, &mineDocument, &theirsDocument}
+    for _, document := range documents {
+        *document, err = session.withoutVersionKey(baseReflectValue, *document)
+        if err != nil {
+            return nil, err
+        }
+    }
+
+    mergedDocument, err := session.mergeDocuments(baseDocument, mineDocument, theirsDocument, resolver)
+    if err != nil {
+        return nil, err
+    }
+
+    mergedPointer := reflect.New(baseReflectValue.Type())
+    mergedPointer.Elem().Set(cloneReflectValue(baseReflectValue))
+
+    mergePatch, changed := diffDocuments(baseDocument, mergedDocument)
+    if !changed {
+        return mergedPointer.Interface(), nil
+    }
+
+    encodedMergePatch, err := json.Marshal(mergePatch)
+    if err != nil {
+        return nil, err
+    }
+    err = patcher.Patch(encodedMergePatch, mergedPointer.Interface())
+    if err != nil {
+        return nil, err
+    }
+    return mergedPointer.Interface(), nil
+}
+
+// ThreeWayMergeDocuments merges two raw JSON documents changed from base, key by key.
+func (patcher *Patcher) ThreeWayMergeDocuments(base []byte, mine []byte, theirs []byte, resolver ConflictResolver) ([]byte, error) {
+    documents := make([]interface{}, 3)
+    for index, doc := range [][]byte{base, mine, theirs} {
+        if len(doc) == 0 {
+            continue
+        }
+        err := json.Unmarshal(doc, &documents[index])
+        if err != nil {
+            return nil, err
+        }
+    }
+
+    mergedDocument, err := patcher.newSession(context.Background()).mergeDocuments(documents[0], documents[1], documents[2], resolver)
+    if err != nil {
+        return nil, err
+    }
+    return json.Marshal(mergedDocument)
+}
+
+// documentValue is a value of a merged document, present is false for a key the document does not hold.
+type documentValue struct {
+    value   interface{}
+    present bool
+}
+
+func (session *patchSession) mergeDocuments(baseDocument interface{}, mineDocument interface{}, theirsDocument interface{}, resolver ConflictResolver) (interface{}, error) {
+    conflicts := make([]Conflict, 0)
+    merged, err := session.mergeDocumentValues(
+        documentValue{baseDocument, true}, documentValue{mineDocument, true}, documentValue{theirsDocument, true},
+        "", resolver, &conflicts)
+    if err != nil {
+        return nil, err
+    }
+    if len(conflicts) != 0 {
+        return nil, &MergeConflictError{Conflicts: conflicts}
+    }
+    return merged.value, nil
+}
+
+func (session *patchSession) mergeDocumentValues(base documentValue, mine documentValue, theirs documentValue, path string, resolver ConflictResolver, conflicts *[]Conflict) (documentValue, error) {
+    switch {
+    case reflect.DeepEqual(mine, theirs), reflect.DeepEqual(base, theirs):
+        return mine, nil
+    case reflect.DeepEqual(base, mine):
+        return theirs, nil
+    }
+
+    baseMap, baseIsMap := base.value.(map[string]interface{})
+    mineMap, mineIsMap := mine.value.(map[string]interface{})
+    theirsMap, theirsIsMap := theirs.value.(map[string]interface{})
+    if baseIsMap && mineIsMap && theirsIsMap {
+        err := session.checkDepth(path)
+        if err != nil {
+            return documentValue{}, err
+        }
+
+        mergedMap := make(map[string]interface{})
+        for _, key := range sortedDocumentKeys(baseMap, mineMap, theirsMap) {
+            merged, err := session.mergeDocumentValues(
+                lookupDocumentValue(baseMap, key), lookupDocumentValue(mineMap, key), lookupDocumentValue(theirsMap, key),
+                appendJsonPointer(path, key), resolver, conflicts)
+            if err != nil {
+                return documentValue{}, err
+            }
+            if merged.present {
+                mergedMap[key] = merged.value
+            }
+        }
+        return documentValue{mergedMap, true}, nil
+    }
+
+    conflict := Conflict{Path: path, Base: base.value, Mine: mine.value, Theirs: theirs.value}
+    if resolver == nil {
+        *conflicts = append(*conflicts, conflict)
+        return mine, nil
+    }
+
+    resolved, err := resolver(conflict)
+    if err != nil {
+        return documentValue{}, &FieldError{Path: path, Err: err}
+    }
+    if resolved == nil {
+        return documentValue{}, nil
+    }
+    return documentValue{resolved, true}, nil
+}
+
+func lookupDocumentValue(documentMap map[string]interface{}, key string) documentValue {
+    value, ok := documentMap[key]
+    return documentValue{value, ok}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+    "errors"
+    "reflect"
+    "testing"
+)
+
+func TestThreeWayMerge(t *testing.T) {
+    base := testUser{Name: "John", Age: 30, Meta: map[string]string{"k": "v"}, Address: testAddress{City: "Paris", Zip: "75001"}}
+    mine := testUser{Name: "John", Age: 31, Meta: map[string]string{"k": "v"}, Address: testAddress{City: "Lyon", Zip: "75001"}}
+    theirs := testUser{Name: "Jane", Age: 30, Meta: map[string]string{"k": "w"}, Address: testAddress{City: "Paris", Zip: "69001"}}
+
+    merged, err := ThreeWayMerge(base, &mine, theirs, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    expected := testUser{Name: "Jane", Age: 31, Meta: map[string]string{"k": "w"}, Address: testAddress{City: "Lyon", Zip: "69001"}}
+    if !reflect.DeepEqual(*merged.(*testUser), expected) {
+        t.Fatalf("expected %+v, got %+v", expected, *merged.(*testUser))
+    }
+    if base.Name != "John" || mine.Name != "John" {
+        t.Fatalf("the inputs should be left untouched, got %+v, %+v", base, mine)
+    }
+}
+
+func TestThreeWayMergeConflicts(t *testing.T) {
+    base := testUser{Name: "John", Age: 30, Tags: []string{"a"}}
+    mine := testUser{Name: "Jane", Age: 30, Tags: []string{"a", "b"}}
+    theirs := testUser{Name: "Joan", Age: 30, Tags: []string{"c"}}
+
+    _, err := ThreeWayMerge(base, mine, theirs, nil)
+    var mergeConflictError *MergeConflictError
+    if !errors.Is(err, ErrMergeConflict) || !errors.As(err, &mergeConflictError) {
+        t.Fatalf("expected a MergeConflictError, got %v", err)
+    }
+    expected := []Conflict{
+        {Path: "/name", Base: "John", Mine: "Jane", Theirs: "Joan"},
+        {Path: "/tags", Base: []interface{}{"a"}, Mine: []interface{}{"a", "b"}, Theirs: []interface{}{"c"}},
+    }
+    if !reflect.DeepEqual(mergeConflictError.Conflicts, expected) {
+        t.Fatalf("expected %+v, got %+v", expected, mergeConflictError.Conflicts)
+    }
+
+    merged, err := ThreeWayMerge(base, mine, theirs, PreferTheirs)
+    if err != nil || merged.(*testUser).Name != "Joan" || !reflect.DeepEqual(merged.(*testUser).Tags, []string{"c"}) {
+        t.Fatalf("%v, %+v", err, merged)
+    }
+
+    merged, err = ThreeWayMerge(base, mine, theirs, PreferMine)
+    if err != nil || merged.(*testUser).Name != "Jane" {
+        t.Fatalf("%v, %+v", err, merged)
+    }
+}
+
+func TestThreeWayMergeResolverError(t *testing.T) {
+    resolver := func(conflict Conflict) (interface{}, error) {
+        return nil, errors.New("unresolved")
+    }
+
+    _, err := ThreeWayMerge(testUser{Name: "a"}, testUser{Name: "b"}, testUser{Name: "c"}, resolver)
+    var fieldError *FieldError
+    if !errors.As(err, &fieldError) || fieldError.Path != "/name" {
+        t.Fatalf("expected an error at /name, got %v", err)
+    }
+}
+
+func TestThreeWayMergeDocuments(t *testing.T) {
+    merged, err := ThreeWayMergeDocuments(
+        []byte(`{"a": 1, "b": {"c": 1, "d": 1}, "e": 1}`),
+        []byte(`{"a": 2, "b": {"c": 2, "d": 1}, "e": 1}`),
+        []byte(`{"a": 1, "b": {"c": 1}, "f": 1}`),
+        nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    assertJSONEqual(t, `{"a": 2, "b": {"c": 2}, "f": 1}`, merged)
+
+    _, err = ThreeWayMergeDocuments([]byte(`{"a": 1}`), []byte(`{"a": 2}`), []byte(`{}`), nil)
+    if !errors.Is(err, ErrMergeConflict) {
+        t.Fatalf("a change against a deletion should conflict, got %v", err)
+    }
+
+    merged, err = ThreeWayMergeDocuments([]byte(`{"a": 1}`), []byte(`{"a": 2}`), []byte(`{}`), func(conflict Conflict) (interface{}, error) {
+        return nil, nil
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+    assertJSONEqual(t, `{}`, merged)
+}
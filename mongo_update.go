@@ -0,0 +1,141 @@
+package main
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "reflect"
+    "strings"
+)
+
+const bsonTagName = "bson"
+
+// ToMongoUpdate translates a merge patch on modelType into a MongoDB update document with $set, $unset and $push.
+// Paths are dotted bson tag names, or the Patcher tag without one, and nested objects on struct fields are
+// followed. null unsets the field, except on an Optional which is set to null. Read-only and version fields are
+// left out, like in ToSQLUpdate. The slices at pushPaths, JSON Pointers such as "/tags", get the payload items
+// pushed instead of being set: this is a choice of the caller, the patch itself still replaces those slices.
+func (patcher *Patcher) ToMongoUpdate(patch []byte, modelType reflect.Type, pushPaths ...string) (map[string]interface{}, error) {
+    payloadMap := make(map[string]interface{})
+    err := json.Unmarshal(patch, &payloadMap)
+    if err != nil {
+        return nil, err
+    }
+
+    for modelType.Kind() == reflect.Ptr {
+        modelType = modelType.Elem()
+    }
+    if !isTraversableStructType(modelType) {
+        return nil, fmt.Errorf("%+v should be the struct type: %w", modelType, ErrUnsupportedType)
+    }
+
+    update := map[string]map[string]interface{}{
+        "$set":   make(map[string]interface{}),
+        "$unset": make(map[string]interface{}),
+        "$push":  make(map[string]interface{}),
+    }
+    pushedPaths := make(map[string]bool, len(pushPaths))
+    for _, pushPath := range pushPaths {
+        pushedPaths[pushPath] = true
+    }
+    err = patcher.newSession(context.Background()).collectMongoUpdate(modelType, payloadMap, "", "", pushedPaths, update)
+    if err != nil {
+        return nil, err
+    }
+
+    updateDocument := make(map[string]interface{})
+    for operator, fields := range update {
+        if len(fields) != 0 {
+            updateDocument[operator] = fields
+        }
+    }
+    return updateDocument, nil
+}
+
+func (session *patchSession) collectMongoUpdate(structType reflect.Type, payloadMap map[string]interface{}, fieldPrefix string, path string, pushedPaths map[string]bool, update map[string]map[string]interface{}) error {
+    err := session.checkDepth(path)
+    if err != nil {
+        return err
+    }
+
+    matchedPayloadKeys := make(map[string]bool, len(payloadMap))
+    for index := 0; index < structType.NumField(); index += 1 {
+        structField := structType.Field(index)
+        structFieldJsonTag, err := session.getJsonStructTag(structField)
+        if err != nil {
+            return err
+        }
+        iPayloadValue, ok := payloadMap[structFieldJsonTag]
+        if structFieldJsonTag == "" || !ok {
+            continue
+        }
+        matchedPayloadKeys[structFieldJsonTag] = true
+
+        structFieldPath := appendJsonPointer(path, structFieldJsonTag)
+        fieldTag := parsePatchFieldTag(structField)
+        fieldName := strings.Split(structField.Tag.Get(bsonTagName), ",")[0]
+        if fieldName == "-" || fieldTag.version {
+            continue
+        }
+        if fieldTag.readOnly {
+            if session.readOnlyPolicy == ReadOnlyRejected {
+                return &FieldError{Path: structFieldPath, Err: ErrReadOnlyField}
+            }
+            continue
+        }
+        if fieldName == "" {
+            fieldName = structFieldJsonTag
+        }
+        fieldName = fieldPrefix + fieldName
+
+        structFieldType := structField.Type
+        for structFieldType.Kind() == reflect.Ptr {
+            structFieldType = structFieldType.Elem()
+        }
+        if nestedPayloadMap, isMap := iPayloadValue.(map[string]interface{}); isMap && isTraversableStructType(structFieldType) {
+            err = session.collectMongoUpdate(structFieldType, nestedPayloadMap, fieldName+".", structFieldPath, pushedPaths, update)
+            if err != nil {
+                return err
+            }
+            continue
+        }
+
+        if iPayloadValue == nil && !isOptionalType(structField.Type) {
+            switch session.nullPolicy {
+            case NullIgnored:
+            case NullRejected:
+                return &FieldError{Path: structFieldPath, Err: ErrNullRejected}
+            default:
+                update["$unset"][fieldName] = ""
+            }
+            continue
+        }
+
+        structFieldValue, err := session.newReflectValueFromPayload(structField.Type, iPayloadValue, structFieldPath, fieldTag)
+        if err != nil {
+            return wrapFieldError(structFieldPath, err)
+        }
+
+        if pushedPaths[structFieldPath] {
+            if structFieldValue.Kind() != reflect.Slice {
+                return &FieldError{Path: structFieldPath, Err: fmt.Errorf("Unable to push to %s, it is not a slice: %w", structField.Name, ErrUnsupportedType)}
+            }
+            items := make([]interface{}, 0, structFieldValue.Len())
+            for itemIndex := 0; itemIndex < structFieldValue.Len(); itemIndex += 1 {
+                items = append(items, storageValue(structFieldValue.Index(itemIndex).Interface()))
+            }
+            update["$push"][fieldName] = map[string]interface{}{"$each": items}
+            continue
+        }
+        update["$set"][fieldName] = storageValue(structFieldValue.Interface())
+    }
+
+    if session.strict {
+        for payloadKey := range payloadMap {
+            if !matchedPayloadKeys[payloadKey] {
+                return &FieldError{Path: appendJsonPointer(path, payloadKey), Err: ErrUnknownField}
+            }
+        }
+    }
+    return nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+    "errors"
+    "reflect"
+    "testing"
+)
+
+type testFeed struct {
+    Title    string           `json:"title"`
+    Subtitle Optional[string] `json:"subtitle"`
+    Owner    *testAddress     `json:"owner" bson:"owner_address"`
+    Items    []string         `json:"items"`
+    Labels   []string         `json:"labels"`
+    Secret   string           `json:"secret" bson:"-"`
+    Created  string           `json:"created" patch:"readonly"`
+    Version  int              `json:"version" patch:"version"`
+}
+
+func TestToMongoUpdate(t *testing.T) {
+    update, err := ToMongoUpdate([]byte(`{"title": null, "subtitle": null, "owner": {"city": "Paris"}, "items": ["c"], "labels": ["x"], "secret": "s", "created": "now", "version": 2}`), reflect.TypeOf(&testFeed{}), "/items")
+    if err != nil {
+        t.Fatal(err)
+    }
+    expected := map[string]interface{}{
+        "$set":   map[string]interface{}{"subtitle": nil, "owner_address.city": "Paris", "labels": []string{"x"}},
+        "$unset": map[string]interface{}{"title": ""},
+        "$push":  map[string]interface{}{"items": map[string]interface{}{"$each": []interface{}{"c"}}},
+    }
+    if !reflect.DeepEqual(update, expected) {
+        t.Fatalf("expected %v, got %v", expected, update)
+    }
+
+    update, err = ToMongoUpdate([]byte(`{"title": "a"}`), reflect.TypeOf(testFeed{}))
+    if err != nil || !reflect.DeepEqual(update, map[string]interface{}{"$set": map[string]interface{}{"title": "a"}}) {
+        t.Fatalf("empty groups should be left out, got %v, %v", err, update)
+    }
+}
+
+func TestToMongoUpdateNullPolicies(t *testing.T) {
+    update, err := NewPatcher(WithNullPolicy(NullIgnored)).ToMongoUpdate([]byte(`{"title": null}`), reflect.TypeOf(testFeed{}))
+    if err != nil || len(update) != 0 {
+        t.Fatalf("%v, %v", err, update)
+    }
+
+    _, err = NewPatcher(WithNullPolicy(NullRejected)).ToMongoUpdate([]byte(`{"owner": {"zip": null}}`), reflect.TypeOf(testFeed{}))
+    var fieldError *FieldError
+    if !errors.Is(err, ErrNullRejected) || !errors.As(err, &fieldError) || fieldError.Path != "/owner/zip" {
+        t.Fatalf("expected ErrNullRejected at /owner/zip, got %v", err)
+    }
+}
+
+func TestPushPathsOnlyAffectTheTranslation(t *testing.T) {
+    update, err := ToMongoUpdate([]byte(`{"items": ["c"]}`), reflect.TypeOf(testFeed{}))
+    if err != nil || !reflect.DeepEqual(update, map[string]interface{}{"$set": map[string]interface{}{"items": []string{"c"}}}) {
+        t.Fatalf("a slice should be set unless pushed, got %v, %v", err, update)
+    }
+
+    _, err = ToMongoUpdate([]byte(`{"title": "a"}`), reflect.TypeOf(testFeed{}), "/title")
+    var fieldError *FieldError
+    if !errors.Is(err, ErrUnsupportedType) || !errors.As(err, &fieldError) || fieldError.Path != "/title" {
+        t.Fatalf("expected ErrUnsupportedType at /title, got %v", err)
+    }
+
+    feed := testFeed{Items: []string{"a", "b"}}
+    err = PatchValues([]byte(`{"items": ["c"]}`), &feed)
+    if err != nil || !reflect.DeepEqual(feed.Items, []string{"c"}) {
+        t.Fatalf("Patch should replace the slice, got %v, %v", err, feed.Items)
+    }
+}
@@ -0,0 +1,495 @@
+package main
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "reflect"
+    "strconv"
+    "strings"
+)
+
+var errPathNotFound = fmt.Errorf("Path does not exist: %w", ErrInvalidPatch)
+
+// PatchOperations applies an RFC 6902 JSON Patch to the target. The operations run on the JSON form of the
+// target, named by the json tags, and the result is merged back like a merge patch, so hooks, the validator
+// and the Authorizer apply as usual. Read-only fields and fields without the caller's role are checked on the
+// paths the operations write, while the values of removed or moved items travel along with them.
+// A failing "test" matches ErrPreconditionFailed.
+// A `patch:"version"` field is not written by the operations, test it or use WithExpectedVersion instead.
+func (patcher *Patcher) PatchOperations(src []byte, iTargetPointer interface{}) error {
+    return patcher.PatchOperationsContext(context.Background(), src, iTargetPointer)
+}
+
+func (patcher *Patcher) PatchOperationsContext(ctx context.Context, src []byte, iTargetPointer interface{}) error {
+    operations := make([]Operation, 0)
+    err := json.Unmarshal(src, &operations)
+    if err != nil {
+        return err
+    }
+
+    targetReflectValue, err := getReflectValueFromIStructPointer(iTargetPointer)
+    if err != nil {
+        return err
+    }
+
+    session := patcher.newSession(ctx)
+    baseDocument, err := session.toDocument(targetReflectValue, "")
+    if err != nil {
+        return err
+    }
+
+    document := baseDocument
+    for _, operation := range operations {
+        operation, ok, err := session.protectOperation(targetReflectValue.Type(), document, operation)
+        if err != nil {
+            return err
+        }
+        if !ok {
+            continue
+        }
+        document, err = applyOperation(document, operation)
+        if err != nil {
+            return err
+        }
+    }
+
+    mergePatch, changed := diffDocuments(baseDocument, document)
+    if !changed {
+        return nil
+    }
+    mergePatch, err = session.withoutVersionKey(targetReflectValue, mergePatch)
+    if err != nil {
+        return err
+    }
+    session.documentFromTarget = true
+    return session.apply(plainDocument(mergePatch), iTargetPointer)
+}
+
+// protectOperation checks the paths an operation writes against the protected fields of targetType. A write to a
+// read-only field fails under ReadOnlyRejected and drops the operation otherwise, a write to a field without the
+// caller's role fails with ErrForbidden. The value of an add, replace or copy keeps the protected fields of the
+// value it overwrites. It returns false for an operation to leave out.
+func (session *patchSession) protectOperation(targetType reflect.Type, document interface{}, operation Operation) (Operation, bool, error) {
+    writtenPaths := []string{operation.Path}
+    switch operation.Op {
+    case "add", "replace", "copy", "remove":
+    case "move":
+        writtenPaths = append(writtenPaths, operation.From)
+    default:
+        return operation, true, nil
+    }
+
+    var valueType reflect.Type
+    for _, writtenPath := range writtenPaths {
+        pathType, err := session.resolvePathType(targetType, writtenPath)
+        if errors.Is(err, ErrReadOnlyField) && session.readOnlyPolicy != ReadOnlyRejected {
+            return operation, false, nil
+        }
+        if err != nil {
+            return operation, false, &FieldError{Path: writtenPath, Err: err}
+        }
+        if writtenPath == operation.Path {
+            valueType = pathType
+        }
+    }
+    if operation.Op == "remove" || operation.Op == "move" || valueType == nil || session.protectedFieldsError(valueType, make(map[reflect.Type]bool)) == nil {
+        return operation, true, nil
+    }
+
+    value := operation.Value
+    if operation.Op == "copy" {
+        copiedValue, ok := lookupJsonPointer(document, operation.From)
+        if !ok {
+            return operation, true, nil
+        }
+        value = plainDocument(copiedValue)
+    }
+
+    protectedValue, err := session.protectValue(valueType, value, overwrittenDocumentValue(document, operation), operation.Path)
+    if err != nil {
+        return operation, false, err
+    }
+    if operation.Op == "copy" {
+        return Operation{Op: "add", Path: operation.Path, Value: protectedValue}, true, nil
+    }
+    operation.Value = protectedValue
+    return operation, true, nil
+}
+
+// resolvePathType returns the type of the value at a JSON Pointer into targetType, or nil when the path leaves the
+// typed fields. It fails with ErrReadOnlyField or ErrForbidden when the path crosses a protected field.
+func (session *patchSession) resolvePathType(targetType reflect.Type, path string) (reflect.Type, error) {
+    if path == "" {
+        return targetType, nil
+    }
+
+    for _, token := range splitJsonPointer(path) {
+        for targetType.Kind() == reflect.Ptr {
+            targetType = targetType.Elem()
+        }
+
+        switch targetType.Kind() {
+        case reflect.Slice, reflect.Array, reflect.Map:
+            targetType = targetType.Elem()
+        case reflect.Struct:
+            if !isTraversableStructType(targetType) {
+                return nil, nil
+            }
+            structField, ok := session.lookupStructFieldByJsonTag(targetType, token)
+            if !ok {
+                return nil, nil
+            }
+            fieldTag := parsePatchFieldTag(structField)
+            if fieldTag.readOnly {
+                return nil, ErrReadOnlyField
+            }
+            if session.isProtectedField(fieldTag) {
+                return nil, ErrForbidden
+            }
+            targetType = structField.Type
+        default:
+            return nil, nil
+        }
+    }
+    return targetType, nil
+}
+
+func (session *patchSession) lookupStructFieldByJsonTag(structType reflect.Type, jsonTag string) (reflect.StructField, bool) {
+    for index := 0; index < structType.NumField(); index += 1 {
+        structField := structType.Field(index)
+        if structField.PkgPath != "" {
+            continue
+        }
+        if structFieldJsonTag, err := session.getJsonStructTag(structField); err == nil && structFieldJsonTag == jsonTag {
+            return structField, true
+        }
+    }
+    return reflect.StructField{}, false
+}
+
+// protectValue returns value with the protected fields of valueType taken from currentValue, the value it
+// overwrites, or left out when there is none. Setting a protected field fails like it does in a merge patch.
+// Array items are new, so they take nothing from the current value.
+func (session *patchSession) protectValue(valueType reflect.Type, value interface{}, currentValue interface{}, path string) (interface{}, error) {
+    for valueType.Kind() == reflect.Ptr {
+        valueType = valueType.Elem()
+    }
+
+    switch valueType.Kind() {
+    case reflect.Struct:
+        valueMap, ok := value.(map[string]interface{})
+        if !ok || !isTraversableStructType(valueType) {
+            return value, nil
+        }
+        currentMap, _ := plainDocument(currentValue).(map[string]interface{})
+
+        protectedMap := make(map[string]interface{}, len(valueMap))
+        for key, item := range valueMap {
+            protectedMap[key] = item
+        }
+        for index := 0; index < valueType.NumField(); index += 1 {
+            structField := valueType.Field(index)
+            structFieldJsonTag, err := session.getJsonStructTag(structField)
+            if structField.PkgPath != "" || err != nil || structFieldJsonTag == "" {
+                continue
+            }
+            structFieldPath := appendJsonPointer(path, structFieldJsonTag)
+            fieldTag := parsePatchFieldTag(structField)
+            item, present := valueMap[structFieldJsonTag]
+
+            if !session.isProtectedField(fieldTag) {
+                if present {
+                    protectedMap[structFieldJsonTag], err = session.protectValue(structField.Type, item, currentMap[structFieldJsonTag], structFieldPath)
+                    if err != nil {
+                        return nil, err
+                    }
+                }
+                continue
+            }
+
+            if present && !fieldTag.readOnly {
+                return nil, &FieldError{Path: structFieldPath, Err: ErrForbidden}
+            }
+            if present && session.readOnlyPolicy == ReadOnlyRejected && !fieldTag.key {
+                return nil, &FieldError{Path: structFieldPath, Err: ErrReadOnlyField}
+            }
+            if currentItem, ok := currentMap[structFieldJsonTag]; ok {
+                protectedMap[structFieldJsonTag] = currentItem
+            } else {
+                delete(protectedMap, structFieldJsonTag)
+            }
+        }
+        return protectedMap, nil
+    case reflect.Slice, reflect.Array:
+        items, ok := value.([]interface{})
+        if !ok {
+            return value, nil
+        }
+        protectedItems := make([]interface{}, len(items))
+        for index, item := range items {
+            var err error
+            protectedItems[index], err = session.protectValue(valueType.Elem(), item, nil, appendJsonPointerIndex(path, index))
+            if err != nil {
+                return nil, err
+            }
+        }
+        return protectedItems, nil
+    case reflect.Map:
+        valueMap, ok := value.(map[string]interface{})
+        if !ok {
+            return value, nil
+        }
+        currentMap, _ := plainDocument(currentValue).(map[string]interface{})
+        protectedMap := make(map[string]interface{}, len(valueMap))
+        for key, item := range valueMap {
+            var err error
+            protectedMap[key], err = session.protectValue(valueType.Elem(), item, currentMap[key], appendJsonPointer(path, key))
+            if err != nil {
+                return nil, err
+            }
+        }
+        return protectedMap, nil
+    }
+    return value, nil
+}
+
+// overwrittenDocumentValue returns the value an operation overwrites: the one at its path, unless it inserts an array item.
+func overwrittenDocumentValue(document interface{}, operation Operation) interface{} {
+    if operation.Op != "replace" && operation.Path != "" {
+        parent, ok := lookupJsonPointer(document, operation.Path[:strings.LastIndex(operation.Path, "/")])
+        if _, isArray := parent.([]interface{}); !ok || isArray {
+            return nil
+        }
+    }
+    currentValue, _ := lookupJsonPointer(document, operation.Path)
+    return currentValue
+}
+
+// ApplyOperations applies an RFC 6902 JSON Patch to a schemaless JSON document.
+func (patcher *Patcher) ApplyOperations(doc []byte, src []byte) ([]byte, error) {
+    var document interface{}
+    if len(doc) != 0 {
+        err := json.Unmarshal(doc, &document)
+        if err != nil {
+            return nil, err
+        }
+    }
+
+    operations := make([]Operation, 0)
+    err := json.Unmarshal(src, &operations)
+    if err != nil {
+        return nil, err
+    }
+
+    document, err = applyOperations(document, operations)
+    if err != nil {
+        return nil, err
+    }
+    return json.Marshal(document)
+}
+
+// applyOperations returns the patched document. Objects and arrays along the patched paths are copied, never mutated.
+func applyOperations(document interface{}, operations []Operation) (interface{}, error) {
+    for _, operation := range operations {
+        var err error
+        document, err = applyOperation(document, operation)
+        if err != nil {
+            return nil, err
+        }
+    }
+    return document, nil
+}
+
+func applyOperation(document interface{}, operation Operation) (interface{}, error) {
+    if operation.Path != "" && !strings.HasPrefix(operation.Path, "/") {
+        return nil, &FieldError{Path: operation.Path, Err: fmt.Errorf("Invalid JSON Pointer %q: %w", operation.Path, ErrInvalidPatch)}
+    }
+
+    var patchedDocument interface{}
+    var err error
+    switch operation.Op {
+    case "add":
+        patchedDocument, err = setDocumentValue(document, operation.Path, operation.Value, true)
+    case "remove":
+        patchedDocument, _, err = removeDocumentValue(document, operation.Path)
+    case "replace":
+        if _, ok := lookupJsonPointer(document, operation.Path); !ok {
+            return nil, &FieldError{Path: operation.Path, Err: errPathNotFound}
+        }
+        patchedDocument, err = setDocumentValue(document, operation.Path, operation.Value, false)
+    case "move":
+        if strings.HasPrefix(operation.Path, operation.From+"/") {
+            return nil, &FieldError{Path: operation.Path, Err: fmt.Errorf("Unable to move %s into itself: %w", operation.From, ErrInvalidPatch)}
+        }
+        var movedValue interface{}
+        patchedDocument, movedValue, err = removeDocumentValue(document, operation.From)
+        if err != nil {
+            return nil, &FieldError{Path: operation.From, Err: err}
+        }
+        patchedDocument, err = setDocumentValue(patchedDocument, operation.Path, movedValue, true)
+    case "copy":
+        copiedValue, ok := lookupJsonPointer(document, operation.From)
+        if !ok {
+            return nil, &FieldError{Path: operation.From, Err: errPathNotFound}
+        }
+        patchedDocument, err = setDocumentValue(document, operation.Path, copiedValue, true)
+    case "test":
+        return document, checkDocumentTest(document, Test{Path: operation.Path, Value: operation.Value})
+    default:
+        return nil, &FieldError{Path: operation.Path, Err: fmt.Errorf("Unknown operation %q: %w", operation.Op, ErrInvalidPatch)}
+    }
+    if err != nil {
+        return nil, &FieldError{Path: operation.Path, Err: err}
+    }
+    return patchedDocument, nil
+}
+
+// setDocumentValue stores value at path. With insert, array items are inserted and "-" appends, otherwise the
+// item at the index is replaced.
+func setDocumentValue(document interface{}, path string, value interface{}, insert bool) (interface{}, error) {
+    if path == "" {
+        return value, nil
+    }
+
+    token, childPath := splitFirstJsonPointerToken(path)
+    switch container := document.(type) {
+    case map[string]interface{}, wholeDocument:
+        documentMap := copyDocumentMap(container)
+        if childPath == "" {
+            documentMap[token] = value
+            return wrapDocumentMap(container, documentMap), nil
+        }
+
+        child, ok := documentMap[token]
+        if !ok {
+            return nil, errPathNotFound
+        }
+        patchedChild, err := setDocumentValue(child, childPath, value, insert)
+        if err != nil {
+            return nil, err
+        }
+        documentMap[token] = patchedChild
+        return wrapDocumentMap(container, documentMap), nil
+    case []interface{}:
+        documentSlice := make([]interface{}, len(container), len(container)+1)
+        copy(documentSlice, container)
+
+        if childPath == "" && insert {
+            if token == "-" {
+                return append(documentSlice, value), nil
+            }
+            index, err := parseArrayIndex(token, len(documentSlice)+1)
+            if err != nil {
+                return nil, err
+            }
+            documentSlice = append(documentSlice, nil)
+            copy(documentSlice[index+1:], documentSlice[index:])
+            documentSlice[index] = value
+            return documentSlice, nil
+        }
+
+        index, err := parseArrayIndex(token, len(documentSlice))
+        if err != nil {
+            return nil, err
+        }
+        if childPath == "" {
+            documentSlice[index] = value
+            return documentSlice, nil
+        }
+        documentSlice[index], err = setDocumentValue(documentSlice[index], childPath, value, insert)
+        if err != nil {
+            return nil, err
+        }
+        return documentSlice, nil
+    }
+    return nil, errPathNotFound
+}
+
+// removeDocumentValue returns the document without the value at path, and the removed value.
+func removeDocumentValue(document interface{}, path string) (interface{}, interface{}, error) {
+    if path == "" {
+        return nil, document, nil
+    }
+
+    token, childPath := splitFirstJsonPointerToken(path)
+    switch container := document.(type) {
+    case map[string]interface{}, wholeDocument:
+        documentMap := copyDocumentMap(container)
+        child, ok := documentMap[token]
+        if !ok {
+            return nil, nil, errPathNotFound
+        }
+        if childPath == "" {
+            delete(documentMap, token)
+            return wrapDocumentMap(container, documentMap), child, nil
+        }
+
+        patchedChild, removedValue, err := removeDocumentValue(child, childPath)
+        if err != nil {
+            return nil, nil, err
+        }
+        documentMap[token] = patchedChild
+        return wrapDocumentMap(container, documentMap), removedValue, nil
+    case []interface{}:
+        index, err := parseArrayIndex(token, len(container))
+        if err != nil {
+            return nil, nil, err
+        }
+        if childPath == "" {
+            documentSlice := make([]interface{}, 0, len(container)-1)
+            documentSlice = append(documentSlice, container[:index]...)
+            documentSlice = append(documentSlice, container[index+1:]...)
+            return documentSlice, container[index], nil
+        }
+
+        documentSlice := make([]interface{}, len(container))
+        copy(documentSlice, container)
+        patchedChild, removedValue, err := removeDocumentValue(container[index], childPath)
+        if err != nil {
+            return nil, nil, err
+        }
+        documentSlice[index] = patchedChild
+        return documentSlice, removedValue, nil
+    }
+    return nil, nil, errPathNotFound
+}
+
+// splitFirstJsonPointerToken returns the unescaped first reference token of a non-empty JSON Pointer and the rest.
+func splitFirstJsonPointerToken(path string) (string, string) {
+    rest := ""
+    if index := strings.Index(path[1:], "/"); index >= 0 {
+        path, rest = path[:index+1], path[index+1:]
+    }
+    return splitJsonPointer(path)[0], rest
+}
+
+func parseArrayIndex(token string, length int) (int, error) {
+    index, err := strconv.Atoi(token)
+    if err != nil || index < 0 || index >= length || (len(token) > 1 && token[0] == '0') {
+        return 0, fmt.Errorf("Invalid array index %q: %w", token, ErrInvalidPatch)
+    }
+    return index, nil
+}
+
+func copyDocumentMap(container interface{}) map[string]interface{} {
+    sourceMap, ok := container.(map[string]interface{})
+    if !ok {
+        sourceMap = container.(wholeDocument)
+    }
+
+    documentMap := make(map[string]interface{}, len(sourceMap)+1)
+    for key, value := range sourceMap {
+        documentMap[key] = value
+    }
+    return documentMap
+}
+
+// wrapDocumentMap keeps a map the diff sends whole marked as such after it was copied.
+func wrapDocumentMap(container interface{}, documentMap map[string]interface{}) interface{} {
+    if _, ok := container.(wholeDocument); ok {
+        return wholeDocument(documentMap)
+    }
+    return documentMap
+}
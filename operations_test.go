@@ -0,0 +1,147 @@
+package main
+
+import (
+    "context"
+    "errors"
+    "reflect"
+    "testing"
+)
+
+func TestPatchOperations(t *testing.T) {
+    user := testUser{Name: "John", Tags: []string{"a", "b"}, Meta: map[string]string{"k": "v"}}
+
+    err := PatchOperations([]byte(`[
+        {"op": "replace", "path": "/name", "value": "Jane"},
+        {"op": "add", "path": "/tags/1", "value": "c"},
+        {"op": "add", "path": "/tags/-", "value": "d"},
+        {"op": "remove", "path": "/tags/0"},
+        {"op": "add", "path": "/meta/x", "value": "y"},
+        {"op": "move", "from": "/meta/k", "path": "/meta/z"},
+        {"op": "copy", "from": "/name", "path": "/address/city"},
+        {"op": "test", "path": "/address/city", "value": "Jane"}
+    ]`), &user)
+    if err != nil {
+        t.Fatal(err)
+    }
+    expected := testUser{Name: "Jane", Tags: []string{"c", "b", "d"}, Meta: map[string]string{"x": "y", "z": "v"}, Address: testAddress{City: "Jane"}}
+    if !reflect.DeepEqual(user, expected) {
+        t.Fatalf("expected %+v, got %+v", expected, user)
+    }
+}
+
+func TestPatchOperationsTestFails(t *testing.T) {
+    user := testUser{Name: "John"}
+
+    err := PatchOperations([]byte(`[{"op": "replace", "path": "/age", "value": 30}, {"op": "test", "path": "/name", "value": "Jane"}]`), &user)
+    var fieldError *FieldError
+    if !errors.Is(err, ErrPreconditionFailed) || !errors.As(err, &fieldError) || fieldError.Path != "/name" {
+        t.Fatalf("expected ErrPreconditionFailed at /name, got %v", err)
+    }
+    if user.Age != 0 {
+        t.Fatalf("a failed test should leave the target untouched, got %+v", user)
+    }
+}
+
+func TestPatchOperationsGoThroughTheEngine(t *testing.T) {
+    err := PatchOperations([]byte(`[{"op": "replace", "path": "/age", "value": "x"}]`), &testUser{})
+    var fieldError *FieldError
+    if !errors.Is(err, ErrTypeMismatch) || !errors.As(err, &fieldError) || fieldError.Path != "/age" {
+        t.Fatalf("expected ErrTypeMismatch at /age, got %v", err)
+    }
+}
+
+func TestApplyOperations(t *testing.T) {
+    patched, err := ApplyOperations([]byte(`{"a": {"b": [1, 2]}}`), []byte(`[{"op": "add", "path": "/a/b/0", "value": 0}, {"op": "copy", "from": "/a/b", "path": "/c"}, {"op": "test", "path": "/c/2", "value": 2}]`))
+    if err != nil {
+        t.Fatal(err)
+    }
+    assertJSONEqual(t, `{"a": {"b": [0, 1, 2]}, "c": [0, 1, 2]}`, patched)
+
+    patched, err = ApplyOperations([]byte(`{"a": 1}`), []byte(`[{"op": "replace", "path": "", "value": [1]}]`))
+    if err != nil {
+        t.Fatal(err)
+    }
+    assertJSONEqual(t, `[1]`, patched)
+}
+
+func TestApplyOperationsErrors(t *testing.T) {
+    for operations, expectedPath := range map[string]string{
+        `[{"op": "replace", "path": "/missing", "value": 1}]`: "/missing",
+        `[{"op": "remove", "path": "/a/5"}]`:                  "/a/5",
+        `[{"op": "add", "path": "/a/01", "value": 1}]`:        "/a/01",
+        `[{"op": "move", "from": "/b", "path": "/b/c"}]`:      "/b/c",
+        `[{"op": "copy", "from": "/missing", "path": "/c"}]`:  "/missing",
+        `[{"op": "unknown", "path": "/a"}]`:                   "/a",
+        `[{"op": "add", "path": "a", "value": 1}]`:            "a",
+        `[{"op": "add", "path": "/c"}]`:                       "/c",
+        `[{"op": "copy", "path": "/c"}]`:                      "/c",
+        `[{"op": "remove"}]`:                                  "",
+    } {
+        _, err := ApplyOperations([]byte(`{"a": [1], "b": {}}`), []byte(operations))
+        var fieldError *FieldError
+        if !errors.Is(err, ErrInvalidPatch) || !errors.As(err, &fieldError) || fieldError.Path != expectedPath {
+            t.Fatalf("%s: expected ErrInvalidPatch at %s, got %v", operations, expectedPath, err)
+        }
+    }
+}
+
+func TestPatchOperationsNeedAValue(t *testing.T) {
+    user := testUser{Name: "John"}
+
+    err := PatchOperations([]byte(`[{"op": "add", "path": "/name"}]`), &user)
+    if !errors.Is(err, ErrInvalidPatch) || user.Name != "John" {
+        t.Fatalf("expected ErrInvalidPatch, got %v, %+v", err, user)
+    }
+
+    err = PatchOperations([]byte(`[{"op": "replace", "path": "/name", "value": null}]`), &user)
+    if err != nil || user.Name != "" {
+        t.Fatalf("%v, %+v", err, user)
+    }
+}
+
+func TestPatchOperationsMoveReadOnlyValuesWithTheirItems(t *testing.T) {
+    model := testReadOnlyModel{Items: []testReadOnlyItem{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}}}
+
+    err := PatchOperations([]byte(`[{"op": "remove", "path": "/items/0"}]`), &model)
+    if err != nil || !reflect.DeepEqual(model.Items, []testReadOnlyItem{{ID: 2, Name: "b"}}) {
+        t.Fatalf("%v, %+v", err, model.Items)
+    }
+
+    err = PatchOperations([]byte(`[
+        {"op": "add", "path": "/items/0", "value": {"id": 9, "name": "c"}},
+        {"op": "move", "from": "/items/1", "path": "/items/0"},
+        {"op": "replace", "path": "/items/1", "value": {"name": "d"}},
+        {"op": "replace", "path": "/owner", "value": {"id": 9, "name": "e"}},
+        {"op": "replace", "path": "/items/0/id", "value": 9}
+    ]`), &model)
+    expected := []testReadOnlyItem{{ID: 2, Name: "b"}, {ID: 0, Name: "d"}}
+    if err != nil || !reflect.DeepEqual(model.Items, expected) || model.Owner != (testReadOnlyItem{Name: "e"}) {
+        t.Fatalf("%v: expected %+v, got %+v", err, expected, model)
+    }
+
+    err = NewPatcher(WithReadOnlyPolicy(ReadOnlyRejected)).PatchOperations([]byte(`[{"op": "add", "path": "/items/-", "value": {"id": 9}}]`), &model)
+    var fieldError *FieldError
+    if !errors.Is(err, ErrReadOnlyField) || !errors.As(err, &fieldError) || fieldError.Path != "/items/-/id" {
+        t.Fatalf("expected ErrReadOnlyField at /items/-/id, got %v", err)
+    }
+}
+
+func TestPatchOperationsCheckPermissions(t *testing.T) {
+    type team struct {
+        Members []testAccount `json:"members"`
+    }
+
+    model := team{Members: []testAccount{{Name: "a", Role: "admin"}, {Name: "b", Role: "owner"}}}
+    ctx := WithRoles(context.Background(), "user")
+
+    err := NewPatcher().PatchOperationsContext(ctx, []byte(`[{"op": "remove", "path": "/members/0"}, {"op": "replace", "path": "/members/0/name", "value": "c"}]`), &model)
+    if err != nil || !reflect.DeepEqual(model.Members, []testAccount{{Name: "c", Role: "owner"}}) {
+        t.Fatalf("%v, %+v", err, model.Members)
+    }
+
+    err = NewPatcher().PatchOperationsContext(ctx, []byte(`[{"op": "replace", "path": "/members/0/role", "value": "admin"}]`), &model)
+    var fieldError *FieldError
+    if !errors.Is(err, ErrForbidden) || !errors.As(err, &fieldError) || fieldError.Path != "/members/0/role" {
+        t.Fatalf("expected ErrForbidden at /members/0/role, got %v", err)
+    }
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+    "encoding/json"
+    "reflect"
+)
+
+type optionalState uint8
+
+const (
+    optionalAbsent optionalState = iota
+    optionalNull
+    optionalPresent
+)
+
+// Optional tells a field which was not sent from one sent as null and one sent with a value.
+// The zero value is absent; the patch sets it to null or to a value, whatever the null policy.
+type Optional[T any] struct {
+    value T
+    state optionalState
+}
+
+func Some[T any](value T) Optional[T] {
+    return Optional[T]{value: value, state: optionalPresent}
+}
+
+func Null[T any]() Optional[T] {
+    return Optional[T]{state: optionalNull}
+}
+
+func (optional Optional[T]) IsAbsent() bool {
+    return optional.state == optionalAbsent
+}
+
+func (optional Optional[T]) IsNull() bool {
+    return optional.state == optionalNull
+}
+
+func (optional Optional[T]) IsPresent() bool {
+    return optional.state == optionalPresent
+}
+
+func (optional Optional[T]) Get() (T, bool) {
+    return optional.value, optional.state == optionalPresent
+}
+
+func (optional Optional[T]) ValueOr(fallback T) T {
+    if optional.state != optionalPresent {
+        return fallback
+    }
+    return optional.value
+}
+
+func (optional Optional[T]) MarshalJSON() ([]byte, error) {
+    if optional.state != optionalPresent {
+        return []byte("null"), nil
+    }
+    return json.Marshal(optional.value)
+}
+
+func (optional *Optional[T]) UnmarshalJSON(data []byte) error {
+    if string(data) == "null" {
+        *optional = Null[T]()
+        return nil
+    }
+
+    var value T
+    err := json.Unmarshal(data, &value)
+    if err != nil {
+        return err
+    }
+    *optional = Some(value)
+    return nil
+}
+
+// optionalField lets the engine fill an Optional[T] without knowing T.
+type optionalField interface {
+    optionalValueType() reflect.Type
+    setOptionalNull()
+    setOptionalValue(valueReflectValue reflect.Value)
+}
+
+// optionalValuer reports an Optional[T] in change sets: its value when present, nil otherwise, and its state.
+type optionalValuer interface {
+    optionalChangeSetValue() interface{}
+    optionalChangeSetState() string
+}
+
+func (optional *Optional[T]) optionalValueType() reflect.Type {
+    return reflect.TypeOf(&optional.value).Elem()
+}
+
+func (optional *Optional[T]) setOptionalNull() {
+    *optional = Null[T]()
+}
+
+func (optional *Optional[T]) setOptionalValue(valueReflectValue reflect.Value) {
+    reflect.ValueOf(&optional.value).Elem().Set(valueReflectValue)
+    optional.state = optionalPresent
+}
+
+func (optional Optional[T]) optionalChangeSetValue() interface{} {
+    if optional.state != optionalPresent {
+        return nil
+    }
+    return changeSetValue(optional.value)
+}
+
+func (optional Optional[T]) optionalChangeSetState() string {
+    switch optional.state {
+    case optionalNull:
+        return "null"
+    case optionalPresent:
+        return "value"
+    }
+    return "absent"
+}
+
+var optionalFieldType = reflect.TypeOf((*optionalField)(nil)).Elem()
+
+func isOptionalType(targetType reflect.Type) bool {
+    return reflect.PtrTo(targetType).Implements(optionalFieldType)
+}
+
+func (session *patchSession) mergePayloadToOptionalSF(structFieldValue reflect.Value, iPayloadValue interface{}, path string, fieldTag patchFieldTag) error {
+    structFieldDataType, err := helperCheckSettabilityAndSFDataType(structFieldValue)
+    if err != nil {
+        return err
+    }
+
+    optionalPointer := reflect.New(structFieldDataType)
+    optional := optionalPointer.Interface().(optionalField)
+    if iPayloadValue == nil {
+        optional.setOptionalNull()
+    } else {
+        valueReflectValue, err := session.newReflectValueFromPayload(optional.optionalValueType(), iPayloadValue, path, fieldTag)
+        if err != nil {
+            return err
+        }
+        optional.setOptionalValue(valueReflectValue)
+    }
+
+    structFieldValue.Set(optionalPointer.Elem())
+    return nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+    "encoding/json"
+    "testing"
+)
+
+type testNickname struct {
+    Nickname Optional[string]  `json:"nickname"`
+    Scores   []Optional[int]   `json:"scores"`
+    Inner    testNicknameInner `json:"inner"`
+}
+
+type testNicknameInner struct {
+    Note Optional[string] `json:"note"`
+}
+
+func TestOptionalStates(t *testing.T) {
+    value := testNickname{}
+    if !value.Nickname.IsAbsent() {
+        t.Fatal("a zero Optional should be absent")
+    }
+
+    err := PatchValues([]byte(`{"nickname": null, "scores": [1, null], "inner": {"note": "hi"}}`), &value)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !value.Nickname.IsNull() || value.Nickname.ValueOr("none") != "none" {
+        t.Fatalf("expected a null nickname, got %+v", value.Nickname)
+    }
+    if score, ok := value.Scores[0].Get(); !ok || score != 1 || !value.Scores[1].IsNull() {
+        t.Fatalf("unexpected scores %+v", value.Scores)
+    }
+    if note, ok := value.Inner.Note.Get(); !ok || note != "hi" {
+        t.Fatalf("unexpected note %+v", value.Inner.Note)
+    }
+
+    err = PatchValues([]byte(`{"nickname": "jj"}`), &value)
+    if nickname, ok := value.Nickname.Get(); err != nil || !ok || nickname != "jj" || !value.Inner.Note.IsPresent() {
+        t.Fatalf("%v, %+v", err, value)
+    }
+
+    err = PatchValues([]byte(`{"nickname": 1}`), &value)
+    if err == nil {
+        t.Fatal("expected a type mismatch")
+    }
+}
+
+func TestOptionalIgnoresNullPolicy(t *testing.T) {
+    value := testNickname{Nickname: Some("jj")}
+    patcher := NewPatcher(WithNullPolicy(NullRejected))
+
+    err := patcher.Patch([]byte(`{"nickname": null}`), &value)
+    if err != nil || !value.Nickname.IsNull() {
+        t.Fatalf("%v, %+v", err, value.Nickname)
+    }
+}
+
+func TestOptionalJSON(t *testing.T) {
+    encoded, err := json.Marshal(testNicknameInner{Note: Some("hi")})
+    if err != nil || string(encoded) != `{"note":"hi"}` {
+        t.Fatalf("%v, %s", err, encoded)
+    }
+    encoded, _ = json.Marshal(testNicknameInner{Note: Null[string]()})
+    if string(encoded) != `{"note":null}` {
+        t.Fatalf("unexpected %s", encoded)
+    }
+
+    var inner testNicknameInner
+    err = json.Unmarshal([]byte(`{"note": null}`), &inner)
+    if err != nil || !inner.Note.IsNull() {
+        t.Fatalf("%v, %+v", err, inner)
+    }
+}
+
+func TestChangeSetReportsOptionalStates(t *testing.T) {
+    value := testNickname{Inner: testNicknameInner{Note: Some("hi")}}
+
+    changes, err := PatchValuesWithChangeSet([]byte(`{"nickname": null, "inner": {"note": "hi"}}`), &value)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    nicknameChange, ok := changes.Lookup("/nickname")
+    if !ok || nicknameChange.OldValue != nil || nicknameChange.NewValue != nil {
+        t.Fatalf("unexpected /nickname change %+v", nicknameChange)
+    }
+    if nicknameChange.OldState != "absent" || nicknameChange.NewState != "null" {
+        t.Fatalf("expected absent to null, got %+v", nicknameChange)
+    }
+    if len(changes.Unchanged) != 1 || changes.Unchanged[0].OldState != "value" || changes.Unchanged[0].NewValue != "hi" {
+        t.Fatalf("unexpected unchanged %+v", changes.Unchanged)
+    }
+
+    inversePatch, err := changes.InversePatch()
+    if err != nil || string(inversePatch) != `{"nickname":null}` {
+        t.Fatalf("%v, %s", err, inversePatch)
+    }
+}
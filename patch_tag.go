@@ -0,0 +1,57 @@
+package main
+
+import (
+    "reflect"
+    "strings"
+)
+
+const patchTagName = "patch"
+
+// patchFieldTag holds the options of the `patch` struct tag, e.g. `patch:"readonly"` or `patch:"perm=admin|owner"`.
+type patchFieldTag struct {
+    readOnly      bool
+    permissions   []string
+    converterName string
+    coerce        bool
+    layout        string
+    version       bool
+    key           bool
+}
+
+func parsePatchFieldTag(structField reflect.StructField) patchFieldTag {
+    fieldTag := patchFieldTag{}
+    for _, tagOption := range strings.Split(structField.Tag.Get(patchTagName), ",") {
+        tagOptionName, tagOptionValue := splitPatchTagOption(tagOption)
+        switch tagOptionName {
+        case "readonly", "-":
+            fieldTag.readOnly = true
+        case "perm":
+            fieldTag.permissions = strings.Split(tagOptionValue, "|")
+        case "conv":
+            fieldTag.converterName = tagOptionValue
+        case "coerce":
+            fieldTag.coerce = true
+        case "layout":
+            fieldTag.layout = tagOptionValue
+        case "version":
+            fieldTag.version = true
+        case "key":
+            fieldTag.key = true
+        }
+    }
+    return fieldTag
+}
+
+// itemTag is the tag applied to slice items and map values: everything but the converter of the field itself.
+func (fieldTag patchFieldTag) itemTag() patchFieldTag {
+    fieldTag.converterName = ""
+    return fieldTag
+}
+
+func splitPatchTagOption(tagOption string) (string, string) {
+    tagOption = strings.TrimSpace(tagOption)
+    if index := strings.Index(tagOption, "="); index >= 0 {
+        return tagOption[:index], tagOption[index+1:]
+    }
+    return tagOption, ""
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+    "errors"
+    "reflect"
+    "testing"
+)
+
+type testReadOnlyItem struct {
+    ID   int    `json:"id" patch:"readonly"`
+    Name string `json:"name"`
+}
+
+type testReadOnlyModel struct {
+    ID     int                         `json:"id" patch:"-"`
+    Owner  testReadOnlyItem            `json:"owner"`
+    Items  []testReadOnlyItem          `json:"items"`
+    Refs   []*testReadOnlyItem         `json:"refs"`
+    ByName map[string]testReadOnlyItem `json:"by_name"`
+}
+
+func TestReadOnlyFieldsAreIgnored(t *testing.T) {
+    model := testReadOnlyModel{ID: 1, Owner: testReadOnlyItem{ID: 2, Name: "a"}}
+
+    err := NewPatcher(WithStrict(true)).Patch([]byte(`{"id": 9, "owner": {"id": 9, "name": "b"}}`), &model)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if model.ID != 1 || model.Owner.ID != 2 || model.Owner.Name != "b" {
+        t.Fatalf("unexpected %+v", model)
+    }
+}
+
+func TestReadOnlyFieldsOfSliceItemsAreKept(t *testing.T) {
+    model := testReadOnlyModel{
+        Items: []testReadOnlyItem{{ID: 7, Name: "x"}},
+        Refs:  []*testReadOnlyItem{{ID: 8, Name: "x"}},
+    }
+
+    err := PatchValues([]byte(`{"items": [{"id": 99, "name": "y"}], "refs": [{"name": "y"}]}`), &model)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    expectedItems := []testReadOnlyItem{{ID: 7, Name: "y"}}
+    if !reflect.DeepEqual(model.Items, expectedItems) {
+        t.Fatalf("expected %+v, got %+v", expectedItems, model.Items)
+    }
+    if model.Refs[0].ID != 8 || model.Refs[0].Name != "y" {
+        t.Fatalf("unexpected %+v", model.Refs[0])
+    }
+}
+
+func TestRemovingSliceItemsWithReadOnlyFieldsNeedsAKey(t *testing.T) {
+    model := testReadOnlyModel{Items: []testReadOnlyItem{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}}}
+
+    err := PatchValues([]byte(`{"items": [{"name": "b"}]}`), &model)
+    var fieldError *FieldError
+    if !errors.Is(err, ErrReadOnlyField) || !errors.As(err, &fieldError) || fieldError.Path != "/items" {
+        t.Fatalf("expected ErrReadOnlyField at /items, got %v", err)
+    }
+    if len(model.Items) != 2 || model.Items[1] != (testReadOnlyItem{ID: 2, Name: "b"}) {
+        t.Fatalf("a rejected patch should leave the target untouched, got %+v", model.Items)
+    }
+
+    err = PatchValues([]byte(`{"items": []}`), &model)
+    if err != nil || len(model.Items) != 0 {
+        t.Fatalf("clearing the slice should not need a key, got %v, %+v", err, model.Items)
+    }
+}
+
+func TestSliceItemsAreMatchedOnTheirKey(t *testing.T) {
+    type keyedItem struct {
+        ID    int    `json:"id" patch:"readonly,key"`
+        Name  string `json:"name"`
+        Owner string `json:"owner" patch:"readonly"`
+    }
+    type keyedModel struct {
+        Items []keyedItem `json:"items"`
+    }
+
+    model := keyedModel{Items: []keyedItem{{ID: 1, Name: "a", Owner: "x"}, {ID: 2, Name: "b", Owner: "y"}}}
+
+    err := PatchValues([]byte(`{"items": [{"id": 2, "name": "B", "owner": "z"}, {"id": 9, "name": "c"}]}`), &model)
+    if err != nil {
+        t.Fatal(err)
+    }
+    expected := []keyedItem{{ID: 2, Name: "B", Owner: "y"}, {ID: 0, Name: "c"}}
+    if !reflect.DeepEqual(model.Items, expected) {
+        t.Fatalf("expected %+v, got %+v", expected, model.Items)
+    }
+
+    // the key is read for matching only, so it is not rejected
+    err = NewPatcher(WithReadOnlyPolicy(ReadOnlyRejected)).Patch([]byte(`{"items": [{"id": 2, "name": "b"}]}`), &model)
+    if err != nil || !reflect.DeepEqual(model.Items, []keyedItem{{ID: 2, Name: "b", Owner: "y"}}) {
+        t.Fatalf("%v, %+v", err, model.Items)
+    }
+
+    err = PatchValues([]byte(`{"items": [{"id": 2}, {"id": 2}]}`), &model)
+    var fieldError *FieldError
+    if !errors.Is(err, ErrReadOnlyField) || !errors.As(err, &fieldError) || fieldError.Path != "/items/1/id" {
+        t.Fatalf("expected a duplicate key at /items/1/id, got %v", err)
+    }
+}
+
+func TestReadOnlyFieldsOfMapValuesAreKept(t *testing.T) {
+    model := testReadOnlyModel{ByName: map[string]testReadOnlyItem{"a": {ID: 7, Name: "x"}}}
+
+    err := PatchValues([]byte(`{"by_name": {"a": {"id": 99, "name": "y"}, "b": {"id": 99, "name": "z"}}}`), &model)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    expected := map[string]testReadOnlyItem{"a": {ID: 7, Name: "y"}, "b": {ID: 0, Name: "z"}}
+    if !reflect.DeepEqual(model.ByName, expected) {
+        t.Fatalf("expected %+v, got %+v", expected, model.ByName)
+    }
+}
+
+func TestReadOnlyFieldsOfTopLevelSliceItemsAreKept(t *testing.T) {
+    items := []testReadOnlyItem{{ID: 7, Name: "x"}}
+
+    err := PatchValues([]byte(`[{"id": 99, "name": "y"}]`), &items)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !reflect.DeepEqual(items, []testReadOnlyItem{{ID: 7, Name: "y"}}) {
+        t.Fatalf("unexpected %+v", items)
+    }
+}
+
+func TestReadOnlyFieldsAreRejected(t *testing.T) {
+    patcher := NewPatcher(WithReadOnlyPolicy(ReadOnlyRejected))
+    model := testReadOnlyModel{Items: []testReadOnlyItem{{ID: 7}}}
+
+    for payload, expectedPath := range map[string]string{
+        `{"id": 9}`:                            "/id",
+        `{"owner": {"id": 9}}`:                 "/owner/id",
+        `{"items": [{"id": 99, "name": "y"}]}`: "/items/0/id",
+        `{"by_name": {"a": {"id": 99}}}`:       "/by_name/a/id",
+    } {
+        err := patcher.Patch([]byte(payload), &model)
+        var fieldError *FieldError
+        if !errors.Is(err, ErrReadOnlyField) || !errors.As(err, &fieldError) || fieldError.Path != expectedPath {
+            t.Fatalf("%s: expected ErrReadOnlyField at %s, got %v", payload, expectedPath, err)
+        }
+    }
+    if model.Items[0].ID != 7 {
+        t.Fatalf("a rejected patch should leave the target untouched, got %+v", model)
+    }
+}
@@ -0,0 +1,338 @@
+package main
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "io"
+    "reflect"
+    "strconv"
+    "strings"
+)
+
+type NullPolicy int
+
+const (
+    // NullSetsZero resets the field to its zero value (empty map or slice for collections).
+    NullSetsZero NullPolicy = iota
+    // NullIgnored leaves the field untouched.
+    NullIgnored
+    // NullRejected fails the patch.
+    NullRejected
+)
+
+type ReadOnlyPolicy int
+
+const (
+    // ReadOnlyIgnored silently skips payload keys of fields tagged `patch:"readonly"` or `patch:"-"`.
+    ReadOnlyIgnored ReadOnlyPolicy = iota
+    // ReadOnlyRejected fails the patch with ErrReadOnlyField.
+    ReadOnlyRejected
+)
+
+type Option func(patcher *Patcher)
+
+type Patcher struct {
+    tagName          string
+    strict           bool
+    skipUntagged     bool
+    allowMixedArrays bool
+    nullPolicy       NullPolicy
+    readOnlyPolicy   ReadOnlyPolicy
+    maxDepth         int
+    authorizer       Authorizer
+    validator        Validator
+    converters       map[reflect.Type]Converter
+    coerce           bool
+}
+
+var defaultPatcher = NewPatcher()
+
+func NewPatcher(options ...Option) *Patcher {
+    patcher := &Patcher{
+        tagName:        "json",
+        nullPolicy:     NullSetsZero,
+        readOnlyPolicy: ReadOnlyIgnored,
+    }
+    for _, option := range options {
+        option(patcher)
+    }
+    return patcher
+}
+
+// WithTagName changes the struct tag used to match payload keys to fields. Default is "json".
+func WithTagName(tagName string) Option {
+    return func(patcher *Patcher) {
+        patcher.tagName = tagName
+    }
+}
+
+// WithStrict rejects payload keys which do not match any struct field.
+func WithStrict(strict bool) Option {
+    return func(patcher *Patcher) {
+        patcher.strict = strict
+    }
+}
+
+// WithSkipUntagged skips struct fields without a tag instead of failing the patch.
+func WithSkipUntagged(skip bool) Option {
+    return func(patcher *Patcher) {
+        patcher.skipUntagged = skip
+    }
+}
+
+// WithMixedArrays allows payload arrays holding items of different data types.
+func WithMixedArrays(allow bool) Option {
+    return func(patcher *Patcher) {
+        patcher.allowMixedArrays = allow
+    }
+}
+
+func WithNullPolicy(nullPolicy NullPolicy) Option {
+    return func(patcher *Patcher) {
+        patcher.nullPolicy = nullPolicy
+    }
+}
+
+func WithReadOnlyPolicy(readOnlyPolicy ReadOnlyPolicy) Option {
+    return func(patcher *Patcher) {
+        patcher.readOnlyPolicy = readOnlyPolicy
+    }
+}
+
+// WithAuthorizer consults authorizer before each field assignment.
+func WithAuthorizer(authorizer Authorizer) Option {
+    return func(patcher *Patcher) {
+        patcher.authorizer = authorizer
+    }
+}
+
+// WithValidator runs validator on the patched copy before it is written back to the target.
+func WithValidator(validator Validator) Option {
+    return func(patcher *Patcher) {
+        patcher.validator = validator
+    }
+}
+
+// WithMaxDepth limits how deep the payload may nest. Zero means unlimited.
+func WithMaxDepth(maxDepth int) Option {
+    return func(patcher *Patcher) {
+        patcher.maxDepth = maxDepth
+    }
+}
+
+// Patch merges src into the target, a pointer to a struct, a slice or a map.
+// Slices are replaced by the payload array, maps are merged key by key and null deletes a key.
+func (patcher *Patcher) Patch(src []byte, iTargetPointer interface{}) error {
+    return patcher.PatchContext(context.Background(), src, iTargetPointer)
+}
+
+// PatchContext patches like Patch; ctx is handed to the Authorizer and carries the caller roles.
+func (patcher *Patcher) PatchContext(ctx context.Context, src []byte, iTargetPointer interface{}) error {
+    var payload interface{}
+
+    err := json.Unmarshal(src, &payload)
+    if err != nil {
+        return err
+    }
+
+    return patcher.newSession(ctx).apply(payload, iTargetPointer)
+}
+
+func (patcher *Patcher) PatchReader(reader io.Reader, iTargetPointer interface{}) error {
+    var payload interface{}
+
+    err := json.NewDecoder(reader).Decode(&payload)
+    if err != nil {
+        return err
+    }
+
+    return patcher.newSession(context.Background()).apply(payload, iTargetPointer)
+}
+
+// PatchMap applies an already decoded merge patch to a struct. To patch a decoded document, see PatchMapDocument.
+func (patcher *Patcher) PatchMap(payloadMap map[string]interface{}, iStructPointer interface{}) error {
+    return patcher.PatchMapContext(context.Background(), payloadMap, iStructPointer)
+}
+
+func (patcher *Patcher) PatchMapContext(ctx context.Context, payloadMap map[string]interface{}, iStructPointer interface{}) error {
+    return patcher.newSession(ctx).apply(payloadMap, iStructPointer)
+}
+
+// PatchWithChangeSet patches like Patch and reports which leaves were modified.
+func (patcher *Patcher) PatchWithChangeSet(src []byte, iTargetPointer interface{}) (*ChangeSet, error) {
+    var payload interface{}
+
+    err := json.Unmarshal(src, &payload)
+    if err != nil {
+        return nil, err
+    }
+
+    session := patcher.newSession(context.Background())
+    err = session.apply(payload, iTargetPointer)
+    if err != nil {
+        return nil, err
+    }
+
+    return session.changes, nil
+}
+
+// PatchWithInverse patches like Patch and returns the merge patch which undoes it.
+func (patcher *Patcher) PatchWithInverse(src []byte, iTargetPointer interface{}) ([]byte, error) {
+    changes, err := patcher.PatchWithChangeSet(src, iTargetPointer)
+    if err != nil {
+        return nil, err
+    }
+    return changes.InversePatch()
+}
+
+// Preview runs the patch on a copy of the target and returns the copy, leaving the target untouched.
+// The validator runs but BeforePatch and AfterPatch do not, so a dry run has no side effects. A patch which would
+// fail returns neither a copy nor changes, only the error.
+func (patcher *Patcher) Preview(src []byte, iTargetPointer interface{}) (interface{}, *ChangeSet, error) {
+    var payload interface{}
+
+    err := json.Unmarshal(src, &payload)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    targetReflectValue, err := getReflectValueFromIStructPointer(iTargetPointer)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    previewPointer := reflect.New(targetReflectValue.Type())
+    previewPointer.Elem().Set(cloneReflectValue(targetReflectValue))
+
+    session := patcher.newSession(context.Background())
+    session.skipHooks = true
+    err = session.apply(payload, previewPointer.Interface())
+    if err != nil {
+        return nil, nil, err
+    }
+    return previewPointer.Interface(), session.changes, nil
+}
+
+// patchSession carries the configuration and the state of a single patch call.
+type patchSession struct {
+    *Patcher
+    ctx           context.Context
+    changes       *ChangeSet
+    preconditions []Test
+    // skipHooks leaves out BeforePatch and AfterPatch, e.g. for a dry run.
+    skipHooks     bool
+    // copyVersion merges the version field like any other and neither checks nor increments it, e.g. for a copy.
+    copyVersion   bool
+    // documentFromTarget marks a payload built from the target itself, e.g. by JSON Patch operations. Its protected
+    // values are the current ones, moved along with their items, and are merged like any other value.
+    documentFromTarget bool
+}
+
+func (patcher *Patcher) newSession(ctx context.Context) *patchSession {
+    return &patchSession{Patcher: patcher, ctx: ctx, changes: newChangeSet()}
+}
+
+// apply merges the payload into a copy of the target and only writes the copy back once every field succeeded,
+// so a failing patch leaves the target untouched.
+func (session *patchSession) apply(iPayloadValue interface{}, iTargetPointer interface{}) error {
+    targetReflectValue, err := getReflectValueFromIStructPointer(iTargetPointer)
+    if err != nil {
+        return err
+    }
+
+    workingPointer := reflect.New(targetReflectValue.Type())
+    workingPointer.Elem().Set(cloneReflectValue(targetReflectValue))
+    workingReflectValue := workingPointer.Elem()
+
+    err = session.checkPreconditions(workingReflectValue)
+    if err != nil {
+        return err
+    }
+
+    if !session.copyVersion {
+        err = session.checkVersion(workingReflectValue, iPayloadValue)
+        if err != nil {
+            return err
+        }
+    }
+
+    err = session.mergePayloadToTarget(workingReflectValue, iPayloadValue)
+    if err != nil {
+        return err
+    }
+
+    if !session.copyVersion {
+        err = session.incrementVersion(workingReflectValue)
+        if err != nil {
+            return err
+        }
+    }
+
+    if !session.skipHooks {
+        currentPointer := reflect.New(targetReflectValue.Type())
+        currentPointer.Elem().Set(cloneReflectValue(targetReflectValue))
+        err = callBeforePatch(session.ctx, currentPointer.Interface(), session.changes)
+        if err != nil {
+            return err
+        }
+
+        err = callAfterPatch(session.ctx, workingPointer.Interface(), session.changes)
+        if err != nil {
+            return err
+        }
+    }
+
+    if session.validator != nil {
+        err = session.validator.Validate(session.ctx, workingPointer.Interface())
+        if err != nil {
+            return err
+        }
+    }
+
+    targetReflectValue.Set(workingReflectValue)
+    return nil
+}
+
+// mergePayloadToTarget merges the payload into the top-level struct, slice or map without running hooks or the validator.
+func (session *patchSession) mergePayloadToTarget(targetReflectValue reflect.Value, iPayloadValue interface{}) error {
+    switch targetReflectValue.Kind() {
+    case reflect.Struct:
+        payloadMap, ok := iPayloadValue.(map[string]interface{})
+        if !ok {
+            return &FieldError{Err: fmt.Errorf("Invalid payload data for %+v: %w", targetReflectValue.Type(), ErrTypeMismatch)}
+        }
+        return session.traverseStructAndMergeStructFieldsWithPayload(targetReflectValue, payloadMap, "")
+    case reflect.Map:
+        return session.mergePayloadToMapEntries(targetReflectValue, iPayloadValue, "")
+    }
+    return session.mergePayloadToStructField(targetReflectValue, iPayloadValue, "", patchFieldTag{})
+}
+
+func (session *patchSession) checkDepth(path string) error {
+    if session.maxDepth > 0 && strings.Count(path, "/") > session.maxDepth {
+        return &FieldError{Path: path, Err: fmt.Errorf("Payload exceeds the maximum depth of %d: %w", session.maxDepth, ErrMaxDepth)}
+    }
+    return nil
+}
+
+// appendJsonPointer appends a reference token to a JSON Pointer (RFC 6901).
+func appendJsonPointer(path string, token string) string {
+    token = strings.Replace(token, "~", "~0", -1)
+    token = strings.Replace(token, "/", "~1", -1)
+    return path + "/" + token
+}
+
+// splitJsonPointer returns the unescaped reference tokens of a non-empty JSON Pointer.
+func splitJsonPointer(path string) []string {
+    tokens := strings.Split(strings.TrimPrefix(path, "/"), "/")
+    for index, token := range tokens {
+        token = strings.Replace(token, "~1", "/", -1)
+        tokens[index] = strings.Replace(token, "~0", "~", -1)
+    }
+    return tokens
+}
+
+func appendJsonPointerIndex(path string, index int) string {
+    return path + "/" + strconv.Itoa(index)
+}
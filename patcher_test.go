@@ -0,0 +1,213 @@
+package main
+
+import (
+    "errors"
+    "reflect"
+    "strings"
+    "testing"
+)
+
+type testAddress struct {
+    City string `json:"city"`
+    Zip  string `json:"zip"`
+}
+
+type testUser struct {
+    Name    string            `json:"name"`
+    Age     int               `json:"age"`
+    Tags    []string          `json:"tags"`
+    Meta    map[string]string `json:"meta"`
+    Address testAddress       `json:"address"`
+}
+
+func TestPatchValuesMergesNestedFields(t *testing.T) {
+    user := testUser{Name: "Richard", Age: 30, Address: testAddress{City: "Yangon", Zip: "11181"}}
+
+    err := PatchValues([]byte(`{"name": "John", "tags": ["a"], "meta": {"k": "v"}, "address": {"city": "Mandalay"}}`), &user)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    expected := testUser{
+        Name:    "John",
+        Age:     30,
+        Tags:    []string{"a"},
+        Meta:    map[string]string{"k": "v"},
+        Address: testAddress{City: "Mandalay", Zip: "11181"},
+    }
+    if !reflect.DeepEqual(user, expected) {
+        t.Fatalf("expected %+v, got %+v", expected, user)
+    }
+}
+
+func TestPatcherPatchMapAndPatchReader(t *testing.T) {
+    patcher := NewPatcher()
+    user := testUser{Name: "Richard"}
+
+    err := patcher.PatchMap(map[string]interface{}{"age": float64(5)}, &user)
+    if err != nil || user.Age != 5 {
+        t.Fatalf("PatchMap: %v, %+v", err, user)
+    }
+
+    err = patcher.PatchReader(strings.NewReader(`{"name": "John"}`), &user)
+    if err != nil || user.Name != "John" {
+        t.Fatalf("PatchReader: %v, %+v", err, user)
+    }
+}
+
+func TestWithTagName(t *testing.T) {
+    type model struct {
+        Name string `json:"name" form:"full_name"`
+    }
+
+    value := model{}
+    err := NewPatcher(WithTagName("form")).Patch([]byte(`{"full_name": "John", "name": "ignored"}`), &value)
+    if err != nil || value.Name != "John" {
+        t.Fatalf("%v, %+v", err, value)
+    }
+}
+
+func TestWithStrict(t *testing.T) {
+    user := testUser{}
+
+    err := NewPatcher().Patch([]byte(`{"unknown": 1}`), &user)
+    if err != nil {
+        t.Fatalf("unknown keys should be ignored by default: %v", err)
+    }
+
+    err = NewPatcher(WithStrict(true)).Patch([]byte(`{"address": {"unknown": 1}}`), &user)
+    var fieldError *FieldError
+    if !errors.Is(err, ErrUnknownField) || !errors.As(err, &fieldError) || fieldError.Path != "/address/unknown" {
+        t.Fatalf("expected ErrUnknownField at /address/unknown, got %v", err)
+    }
+}
+
+func TestWithSkipUntagged(t *testing.T) {
+    type model struct {
+        Name     string `json:"name"`
+        Internal string
+    }
+
+    value := model{Internal: "kept"}
+    err := NewPatcher().Patch([]byte(`{"name": "John"}`), &value)
+    if err == nil {
+        t.Fatal("expected an error for the untagged field")
+    }
+
+    err = NewPatcher(WithSkipUntagged(true)).Patch([]byte(`{"name": "John", "Internal": "x"}`), &value)
+    if err != nil || value.Name != "John" || value.Internal != "kept" {
+        t.Fatalf("%v, %+v", err, value)
+    }
+}
+
+func TestWithMixedArrays(t *testing.T) {
+    type model struct {
+        Items []interface{} `json:"items"`
+    }
+
+    value := model{}
+    err := NewPatcher().Patch([]byte(`{"items": [1, "a"]}`), &value)
+    if !errors.Is(err, ErrTypeMismatch) {
+        t.Fatalf("expected ErrTypeMismatch, got %v", err)
+    }
+
+    err = NewPatcher(WithMixedArrays(true)).Patch([]byte(`{"items": [1, "a"]}`), &value)
+    if err != nil || len(value.Items) != 2 {
+        t.Fatalf("%v, %+v", err, value)
+    }
+}
+
+func TestWithNullPolicy(t *testing.T) {
+    user := testUser{Name: "John", Tags: []string{"a"}}
+    err := NewPatcher().Patch([]byte(`{"name": null, "tags": null}`), &user)
+    if err != nil || user.Name != "" || user.Tags == nil || len(user.Tags) != 0 {
+        t.Fatalf("NullSetsZero: %v, %+v", err, user)
+    }
+
+    user = testUser{Name: "John"}
+    err = NewPatcher(WithNullPolicy(NullIgnored)).Patch([]byte(`{"name": null}`), &user)
+    if err != nil || user.Name != "John" {
+        t.Fatalf("NullIgnored: %v, %+v", err, user)
+    }
+
+    err = NewPatcher(WithNullPolicy(NullRejected)).Patch([]byte(`{"age": 3, "name": null}`), &user)
+    if !errors.Is(err, ErrNullRejected) {
+        t.Fatalf("NullRejected: expected ErrNullRejected, got %v", err)
+    }
+    if user.Name != "John" || user.Age != 0 {
+        t.Fatalf("a rejected patch should leave the target untouched, got %+v", user)
+    }
+}
+
+func TestWithMaxDepth(t *testing.T) {
+    type model struct {
+        Outer struct {
+            Inner testAddress `json:"inner"`
+        } `json:"outer"`
+    }
+
+    value := model{}
+    err := NewPatcher(WithMaxDepth(1)).Patch([]byte(`{"outer": {"inner": {"city": "Yangon"}}}`), &value)
+    var fieldError *FieldError
+    if !errors.Is(err, ErrMaxDepth) || !errors.As(err, &fieldError) || fieldError.Path != "/outer/inner" {
+        t.Fatalf("expected ErrMaxDepth at /outer/inner, got %v", err)
+    }
+
+    err = NewPatcher(WithMaxDepth(2)).Patch([]byte(`{"outer": {"inner": {"city": "Yangon"}}}`), &value)
+    if err != nil || value.Outer.Inner.City != "Yangon" {
+        t.Fatalf("%v, %+v", err, value)
+    }
+}
+
+func TestPatchRejectsInvalidTargets(t *testing.T) {
+    user := testUser{}
+
+    err := PatchValues([]byte(`{"name": "John"}`), user)
+    if err == nil {
+        t.Fatal("expected an error for a non-pointer target")
+    }
+
+    err = PatchValues([]byte(`{"age": "old"}`), &user)
+    if !errors.Is(err, ErrTypeMismatch) {
+        t.Fatalf("expected ErrTypeMismatch, got %v", err)
+    }
+}
+
+func TestPreviewLeavesTargetUntouched(t *testing.T) {
+    user := testUser{Name: "John", Tags: []string{"a"}, Meta: map[string]string{"k": "v"}}
+
+    preview, changes, err := Preview([]byte(`{"name": "Richard", "tags": ["b"], "meta": {"k": "w"}}`), &user)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    previewUser, ok := preview.(*testUser)
+    if !ok {
+        t.Fatalf("expected a *testUser, got %T", preview)
+    }
+    if previewUser.Name != "Richard" || previewUser.Tags[0] != "b" || previewUser.Meta["k"] != "w" {
+        t.Fatalf("unexpected preview %+v", previewUser)
+    }
+    if user.Name != "John" || user.Tags[0] != "a" || user.Meta["k"] != "v" {
+        t.Fatalf("the target should be untouched, got %+v", user)
+    }
+    if !reflect.DeepEqual(changes.ChangedPaths(), []string{"/name", "/tags", "/meta"}) {
+        t.Fatalf("unexpected changed paths %v", changes.ChangedPaths())
+    }
+}
+
+func TestPreviewReportsErrors(t *testing.T) {
+    user := testUser{Name: "John"}
+
+    preview, changes, err := Preview([]byte(`{"age": 31, "name": 1}`), &user)
+    var fieldError *FieldError
+    if !errors.Is(err, ErrTypeMismatch) || !errors.As(err, &fieldError) || fieldError.Path != "/name" {
+        t.Fatalf("expected ErrTypeMismatch at /name, got %v", err)
+    }
+    if preview != nil || changes != nil {
+        t.Fatalf("a failed preview should return no copy and no changes, got %+v, %+v", preview, changes)
+    }
+    if user.Name != "John" {
+        t.Fatalf("the target should be untouched, got %+v", user)
+    }
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+    "encoding/json"
+    "errors"
+    "net/http"
+)
+
+const problemMediaType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details body. Errors lists each payload field which caused it.
+// A Problem is also an error, so load and save callbacks of Handler can return one to choose the response.
+type Problem struct {
+    Type   string         `json:"type"`
+    Title  string         `json:"title"`
+    Status int            `json:"status"`
+    Detail string         `json:"detail,omitempty"`
+    Errors []ProblemField `json:"errors,omitempty"`
+}
+
+// ProblemField is one entry of the errors extension: the JSON Pointer of the field and what is wrong with it.
+type ProblemField struct {
+    Path    string `json:"path"`
+    Message string `json:"message"`
+}
+
+// NewProblem converts err to a Problem. The status comes from the sentinel errors of this package, e.g.
+// 422 for ErrTypeMismatch or ErrValidation, 409 for ErrVersionConflict, and 500 for any other error.
+// ErrPreconditionFailed comes from a precondition in the body and is a 409, 412 is left to If-Match.
+func NewProblem(err error) *Problem {
+    return newProblem(problemStatus(err, http.StatusInternalServerError), err)
+}
+
+// WriteProblem writes err as an application/problem+json response.
+func WriteProblem(w http.ResponseWriter, err error) {
+    NewProblem(err).Write(w)
+}
+
+func (problem *Problem) Error() string {
+    if problem.Detail == "" {
+        return problem.Title
+    }
+    return problem.Detail
+}
+
+func (problem *Problem) Write(w http.ResponseWriter) {
+    if problem.Type == "" {
+        problem.Type = "about:blank"
+    }
+
+    w.Header().Set("Content-Type", problemMediaType)
+    w.WriteHeader(problem.Status)
+    json.NewEncoder(w).Encode(problem)
+}
+
+// serverErrorDetail replaces the text of errors which are not the engine's own, e.g. a database driver error, in a
+// 5xx Problem so that it is not sent to the client.
+const serverErrorDetail = "The patch could not be applied because of a server error."
+
+// newProblem builds a Problem with status, unless err already is one.
+func newProblem(status int, err error) *Problem {
+    var problem *Problem
+    if errors.As(err, &problem) {
+        return problem
+    }
+
+    if status >= http.StatusInternalServerError && !errors.Is(err, ErrUnsupportedType) {
+        return &Problem{
+            Type:   "about:blank",
+            Title:  http.StatusText(status),
+            Status: status,
+            Detail: serverErrorDetail,
+        }
+    }
+    return &Problem{
+        Type:   "about:blank",
+        Title:  http.StatusText(status),
+        Status: status,
+        Detail: err.Error(),
+        Errors: problemFields(err),
+    }
+}
+
+func writeProblem(w http.ResponseWriter, status int, err error) {
+    newProblem(status, err).Write(w)
+}
+
+func problemFields(err error) []ProblemField {
+    var fieldErrors FieldErrors
+    if errors.As(err, &fieldErrors) {
+        fields := make([]ProblemField, 0, len(fieldErrors))
+        for _, fieldError := range fieldErrors {
+            fields = append(fields, ProblemField{Path: fieldError.Path, Message: fieldError.Err.Error()})
+        }
+        return fields
+    }
+
+    var mergeConflictError *MergeConflictError
+    if errors.As(err, &mergeConflictError) {
+        fields := make([]ProblemField, 0, len(mergeConflictError.Conflicts))
+        for _, conflict := range mergeConflictError.Conflicts {
+            fields = append(fields, ProblemField{Path: conflict.Path, Message: ErrMergeConflict.Error()})
+        }
+        return fields
+    }
+
+    var fieldError *FieldError
+    if errors.As(err, &fieldError) {
+        return []ProblemField{{Path: fieldError.Path, Message: fieldError.Err.Error()}}
+    }
+    return nil
+}
+
+// problemStatus maps the sentinel errors to a status code, fallbackStatus is used for any other error.
+func problemStatus(err error, fallbackStatus int) int {
+    var problem *Problem
+    var syntaxError *json.SyntaxError
+    var unmarshalTypeError *json.UnmarshalTypeError
+
+    switch {
+    case errors.As(err, &problem):
+        return problem.Status
+    case errors.As(err, &syntaxError), errors.As(err, &unmarshalTypeError):
+        return http.StatusBadRequest
+    case errors.Is(err, ErrNotFound):
+        return http.StatusNotFound
+    case errors.Is(err, ErrForbidden):
+        return http.StatusForbidden
+    case errors.Is(err, ErrVersionConflict), errors.Is(err, ErrMergeConflict), errors.Is(err, ErrPreconditionFailed):
+        return http.StatusConflict
+    case errors.Is(err, ErrUnsupportedType):
+        return http.StatusInternalServerError
+    case errors.Is(err, ErrTypeMismatch), errors.Is(err, ErrNullRejected), errors.Is(err, ErrReadOnlyField),
+        errors.Is(err, ErrValidation), errors.Is(err, ErrUnknownField), errors.Is(err, ErrMaxDepth), errors.Is(err, ErrInvalidPatch):
+        return http.StatusUnprocessableEntity
+    }
+    return fallbackStatus
+}
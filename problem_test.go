@@ -0,0 +1,90 @@
+package main
+
+import (
+    "encoding/json"
+    "errors"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+)
+
+func TestProblemStatus(t *testing.T) {
+    var syntaxError error = json.Unmarshal([]byte(`{`), &testUser{})
+
+    for _, testCase := range []struct {
+        err    error
+        status int
+    }{
+        {syntaxError, http.StatusBadRequest},
+        {ErrNotFound, http.StatusNotFound},
+        {&FieldError{Path: "/name", Err: ErrForbidden}, http.StatusForbidden},
+        {&VersionConflictError{Expected: 1, Actual: 2}, http.StatusConflict},
+        {&MergeConflictError{}, http.StatusConflict},
+        {&PreconditionFailedError{}, http.StatusConflict},
+        {&FieldError{Path: "/age", Err: ErrTypeMismatch}, http.StatusUnprocessableEntity},
+        {ErrNullRejected, http.StatusUnprocessableEntity},
+        {ErrReadOnlyField, http.StatusUnprocessableEntity},
+        {ErrValidation, http.StatusUnprocessableEntity},
+        {ErrUnknownField, http.StatusUnprocessableEntity},
+        {ErrMaxDepth, http.StatusUnprocessableEntity},
+        {errPathNotFound, http.StatusUnprocessableEntity},
+        {ErrUnsupportedType, http.StatusInternalServerError},
+        {errors.New("boom"), http.StatusInternalServerError},
+        {&Problem{Status: http.StatusTeapot}, http.StatusTeapot},
+    } {
+        if problem := NewProblem(testCase.err); problem.Status != testCase.status {
+            t.Fatalf("%v: expected %d, got %d", testCase.err, testCase.status, problem.Status)
+        }
+    }
+}
+
+func TestInvalidOperationsAreClientErrors(t *testing.T) {
+    for _, operations := range []string{
+        `[{"op": "replace", "path": "/missing", "value": 1}]`,
+        `[{"op": "add", "path": "/tags/5", "value": "a"}]`,
+        `[{"op": "unknown", "path": "/name"}]`,
+        `[{"op": "add", "path": "name", "value": "a"}]`,
+    } {
+        err := PatchOperations([]byte(operations), &testUser{})
+        if !errors.Is(err, ErrInvalidPatch) || NewProblem(err).Status != http.StatusUnprocessableEntity {
+            t.Fatalf("%s: expected a 422 matching ErrInvalidPatch, got %v", operations, err)
+        }
+    }
+
+    _, err := Compose([]byte(`{"a": 1}`), []byte(`{"a": {"b": 1}}`))
+    if !errors.Is(err, ErrInvalidPatch) {
+        t.Fatalf("expected ErrInvalidPatch, got %v", err)
+    }
+}
+
+func TestProblemFields(t *testing.T) {
+    problem := NewProblem(FieldErrors{
+        {Path: "/name", Err: ErrReadOnlyField},
+        {Path: "/age", Err: ErrTypeMismatch},
+    })
+    expected := []ProblemField{
+        {Path: "/name", Message: ErrReadOnlyField.Error()},
+        {Path: "/age", Message: ErrTypeMismatch.Error()},
+    }
+    if problem.Status != http.StatusUnprocessableEntity || len(problem.Errors) != 2 || problem.Errors[0] != expected[0] || problem.Errors[1] != expected[1] {
+        t.Fatalf("unexpected %+v", problem)
+    }
+
+    problem = NewProblem(&MergeConflictError{Conflicts: []Conflict{{Path: "/name"}}})
+    if len(problem.Errors) != 1 || problem.Errors[0].Path != "/name" {
+        t.Fatalf("unexpected %+v", problem)
+    }
+}
+
+func TestWriteProblem(t *testing.T) {
+    recorder := httptest.NewRecorder()
+    WriteProblem(recorder, &FieldError{Path: "/age", Err: ErrTypeMismatch})
+
+    problem := decodeTestProblem(t, recorder)
+    if recorder.Code != http.StatusUnprocessableEntity || problem.Type != "about:blank" || problem.Title != http.StatusText(http.StatusUnprocessableEntity) {
+        t.Fatalf("unexpected %d %+v", recorder.Code, problem)
+    }
+    if problem.Detail != "/age: "+ErrTypeMismatch.Error() || problem.Errors[0].Path != "/age" {
+        t.Fatalf("unexpected %+v", problem)
+    }
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+    "database/sql"
+    "database/sql/driver"
+    "encoding/json"
+    "fmt"
+    "math"
+    "reflect"
+)
+
+var (
+    scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+    valuerType  = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+)
+
+func implementsScanner(targetType reflect.Type) bool {
+    return reflect.PtrTo(targetType).Implements(scannerType)
+}
+
+// sqlNullValueFieldIndex recognizes sql.NullString and the like: a Scanner struct made of a value field and `Valid bool`.
+func sqlNullValueFieldIndex(targetType reflect.Type) (int, bool) {
+    if targetType.Kind() != reflect.Struct || targetType.NumField() != 2 || !implementsScanner(targetType) {
+        return 0, false
+    }
+
+    validField, ok := targetType.FieldByName("Valid")
+    if !ok || validField.Type.Kind() != reflect.Bool {
+        return 0, false
+    }
+
+    valueFieldIndex := 1 - validField.Index[0]
+    if targetType.Field(valueFieldIndex).PkgPath != "" {
+        return 0, false
+    }
+    return valueFieldIndex, true
+}
+
+// mergePayloadToSqlNullSF maps a JSON scalar to {value, Valid: true} and null to Valid: false.
+func (session *patchSession) mergePayloadToSqlNullSF(structFieldValue reflect.Value, iPayloadValue interface{}, path string, fieldTag patchFieldTag) error {
+    structFieldDataType, err := helperCheckSettabilityAndSFDataType(structFieldValue)
+    if err != nil {
+        return err
+    }
+
+    valueFieldIndex, _ := sqlNullValueFieldIndex(structFieldDataType)
+    nullReflectValue := reflect.New(structFieldDataType).Elem()
+    if iPayloadValue != nil {
+        valueReflectValue, err := session.newReflectValueFromPayload(structFieldDataType.Field(valueFieldIndex).Type, iPayloadValue, path, fieldTag)
+        if err != nil {
+            return err
+        }
+        nullReflectValue.Field(valueFieldIndex).Set(valueReflectValue)
+        nullReflectValue.FieldByName("Valid").SetBool(true)
+    }
+
+    structFieldValue.Set(nullReflectValue)
+    return nil
+}
+
+// mergePayloadToScannerSF feeds the payload to Scan the way a database driver would.
+func mergePayloadToScannerSF(structFieldValue reflect.Value, iPayloadValue interface{}) error {
+    structFieldDataType, err := helperCheckSettabilityAndSFDataType(structFieldValue)
+    if err != nil {
+        return err
+    }
+
+    driverValue, err := payloadToDriverValue(iPayloadValue)
+    if err != nil {
+        return err
+    }
+
+    scannedReflectValue := reflect.New(structFieldDataType)
+    err = scannedReflectValue.Interface().(sql.Scanner).Scan(driverValue)
+    if err != nil {
+        return fmt.Errorf("Invalid payload data for %+v: %s: %w", structFieldDataType, err, ErrTypeMismatch)
+    }
+
+    structFieldValue.Set(scannedReflectValue.Elem())
+    return nil
+}
+
+// payloadToDriverValue turns integral numbers into int64 and objects or arrays into their JSON bytes.
+func payloadToDriverValue(iPayloadValue interface{}) (driver.Value, error) {
+    switch payloadValue := iPayloadValue.(type) {
+    case float64:
+        if payloadValue == math.Trunc(payloadValue) && payloadValue >= math.MinInt64 && payloadValue < math.MaxInt64 {
+            return int64(payloadValue), nil
+        }
+        return payloadValue, nil
+    case map[string]interface{}, []interface{}:
+        return json.Marshal(payloadValue)
+    }
+    return iPayloadValue, nil
+}
+
+// changeSetValue reports driver.Valuer types, e.g. sql.NullString, by their driver value and Optional by its value.
+func changeSetValue(value interface{}) interface{} {
+    if optional, ok := value.(optionalValuer); ok {
+        return optional.optionalChangeSetValue()
+    }
+
+    reflectValue := reflect.ValueOf(value)
+    if !reflectValue.IsValid() || !reflectValue.Type().Implements(valuerType) {
+        return value
+    }
+    if reflectValue.Kind() == reflect.Ptr && reflectValue.IsNil() {
+        return value
+    }
+
+    driverValue, err := value.(driver.Valuer).Value()
+    if err != nil {
+        return value
+    }
+    return driverValue
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+    "database/sql"
+    "errors"
+    "fmt"
+    "strings"
+    "testing"
+    "time"
+)
+
+// testTags is a custom sql.Scanner storing a comma separated column.
+type testTags struct {
+    items []string
+}
+
+func (tags *testTags) Scan(src interface{}) error {
+    text, ok := src.(string)
+    if !ok {
+        return fmt.Errorf("unsupported %T", src)
+    }
+    tags.items = strings.Split(text, ",")
+    return nil
+}
+
+type testRecord struct {
+    Name   sql.NullString  `json:"name"`
+    Count  sql.NullInt64   `json:"count"`
+    Score  sql.NullFloat64 `json:"score"`
+    SeenAt sql.NullTime    `json:"seen_at"`
+    Tags   testTags        `json:"tags"`
+    Flags  []sql.NullBool  `json:"flags"`
+}
+
+func TestSqlNullTypes(t *testing.T) {
+    record := testRecord{Name: sql.NullString{String: "old", Valid: true}}
+
+    err := PatchValues([]byte(`{"name": null, "count": 3, "score": 1.5, "seen_at": "2024-03-01T10:00:00Z", "flags": [true, null]}`), &record)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if record.Name.Valid || record.Count != (sql.NullInt64{Int64: 3, Valid: true}) || record.Score != (sql.NullFloat64{Float64: 1.5, Valid: true}) {
+        t.Fatalf("unexpected %+v", record)
+    }
+    if !record.SeenAt.Valid || !record.SeenAt.Time.Equal(time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)) {
+        t.Fatalf("unexpected seen_at %+v", record.SeenAt)
+    }
+    if record.Flags[0] != (sql.NullBool{Bool: true, Valid: true}) || record.Flags[1].Valid {
+        t.Fatalf("unexpected flags %+v", record.Flags)
+    }
+
+    err = PatchValues([]byte(`{"count": "3"}`), &record)
+    if !errors.Is(err, ErrTypeMismatch) {
+        t.Fatalf("expected ErrTypeMismatch, got %v", err)
+    }
+}
+
+func TestScannerFields(t *testing.T) {
+    record := testRecord{}
+
+    err := PatchValues([]byte(`{"tags": "a,b"}`), &record)
+    if err != nil || strings.Join(record.Tags.items, "|") != "a|b" {
+        t.Fatalf("%v, %+v", err, record.Tags)
+    }
+
+    err = PatchValues([]byte(`{"tags": 1}`), &record)
+    var fieldError *FieldError
+    if !errors.Is(err, ErrTypeMismatch) || !errors.As(err, &fieldError) || fieldError.Path != "/tags" {
+        t.Fatalf("expected ErrTypeMismatch at /tags, got %v", err)
+    }
+}
+
+func TestChangeSetReportsDriverValues(t *testing.T) {
+    record := testRecord{Name: sql.NullString{String: "old", Valid: true}}
+
+    changes, err := PatchValuesWithChangeSet([]byte(`{"name": "new", "count": null}`), &record)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    nameChange, _ := changes.Lookup("/name")
+    if nameChange.OldValue != "old" || nameChange.NewValue != "new" {
+        t.Fatalf("unexpected /name change %+v", nameChange)
+    }
+    if len(changes.Unchanged) != 1 || changes.Unchanged[0].Path != "/count" || changes.Unchanged[0].NewValue != nil {
+        t.Fatalf("unexpected unchanged %+v", changes.Unchanged)
+    }
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "reflect"
+    "strconv"
+    "strings"
+)
+
+const dbTagName = "db"
+
+// Dialect selects the placeholders and identifier quoting of the generated SQL.
+type Dialect int
+
+const (
+    // Postgres numbers placeholders $1, $2 and quotes identifiers with double quotes.
+    Postgres Dialect = iota
+    // MySQL uses ? placeholders and backquotes.
+    MySQL
+    // SQLite uses ? placeholders and double quotes.
+    SQLite
+)
+
+func (dialect Dialect) placeholder(index int) string {
+    if dialect == Postgres {
+        return "$" + strconv.Itoa(index)
+    }
+    return "?"
+}
+
+func (dialect Dialect) quoteIdentifier(name string) string {
+    if dialect == MySQL {
+        return "`" + strings.Replace(name, "`", "``", -1) + "`"
+    }
+    return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+
+// ToSQLUpdate returns the SET clause and the arguments writing only the columns a merge patch sets on modelType,
+// e.g. `SET "name" = $1, "age" = $2`. Columns are named by the db tag, or the Patcher tag without one, and
+// fields tagged `db:"-"`, read-only or version fields are left out. A nested object on a struct field, or a
+// pointer to one, sets the columns of its fields, named parent_child. Optional and driver.Valuer fields give their driver value and
+// pointers are dereferenced, nil being NULL. A patch setting nothing returns an empty clause.
+func (patcher *Patcher) ToSQLUpdate(patch []byte, modelType reflect.Type, dialect Dialect) (string, []interface{}, error) {
+    payloadMap := make(map[string]interface{})
+    err := json.Unmarshal(patch, &payloadMap)
+    if err != nil {
+        return "", nil, err
+    }
+
+    for modelType.Kind() == reflect.Ptr {
+        modelType = modelType.Elem()
+    }
+    if !isTraversableStructType(modelType) {
+        return "", nil, fmt.Errorf("%+v should be the struct type: %w", modelType, ErrUnsupportedType)
+    }
+
+    columns := make([]string, 0)
+    args := make([]interface{}, 0)
+    err = patcher.newSession(context.Background()).collectSQLColumns(modelType, payloadMap, "", "", func(column string, value interface{}) {
+        args = append(args, value)
+        columns = append(columns, dialect.quoteIdentifier(column)+" = "+dialect.placeholder(len(args)))
+    })
+    if err != nil || len(columns) == 0 {
+        return "", nil, err
+    }
+    return "SET " + strings.Join(columns, ", "), args, nil
+}
+
+func (session *patchSession) collectSQLColumns(structType reflect.Type, payloadMap map[string]interface{}, columnPrefix string, path string, addColumn func(column string, value interface{})) error {
+    err := session.checkDepth(path)
+    if err != nil {
+        return err
+    }
+
+    matchedPayloadKeys := make(map[string]bool, len(payloadMap))
+    for index := 0; index < structType.NumField(); index += 1 {
+        structField := structType.Field(index)
+        structFieldJsonTag, err := session.getJsonStructTag(structField)
+        if err != nil {
+            return err
+        }
+        iPayloadValue, ok := payloadMap[structFieldJsonTag]
+        if structFieldJsonTag == "" || !ok {
+            continue
+        }
+        matchedPayloadKeys[structFieldJsonTag] = true
+
+        structFieldPath := appendJsonPointer(path, structFieldJsonTag)
+        fieldTag := parsePatchFieldTag(structField)
+        column := strings.Split(structField.Tag.Get(dbTagName), ",")[0]
+        if column == "-" || fieldTag.version {
+            continue
+        }
+        if fieldTag.readOnly {
+            if session.readOnlyPolicy == ReadOnlyRejected {
+                return &FieldError{Path: structFieldPath, Err: ErrReadOnlyField}
+            }
+            continue
+        }
+        if column == "" {
+            column = structFieldJsonTag
+        }
+        column = columnPrefix + column
+
+        structFieldType := structField.Type
+        for structFieldType.Kind() == reflect.Ptr {
+            structFieldType = structFieldType.Elem()
+        }
+        if nestedPayloadMap, isMap := iPayloadValue.(map[string]interface{}); isMap && isTraversableStructType(structFieldType) {
+            err = session.collectSQLColumns(structFieldType, nestedPayloadMap, column+"_", structFieldPath, addColumn)
+            if err != nil {
+                return err
+            }
+            continue
+        }
+
+        if iPayloadValue == nil && !isOptionalType(structField.Type) {
+            switch session.nullPolicy {
+            case NullIgnored:
+                continue
+            case NullRejected:
+                return &FieldError{Path: structFieldPath, Err: ErrNullRejected}
+            }
+        }
+        if iPayloadValue == nil && structField.Type.Kind() == reflect.Ptr && isTraversableStructType(structFieldType) {
+            err = session.collectNullSQLColumns(structFieldType, column+"_", addColumn)
+            if err != nil {
+                return err
+            }
+            continue
+        }
+
+        structFieldValue, err := session.newReflectValueFromPayload(structField.Type, iPayloadValue, structFieldPath, fieldTag)
+        if err != nil {
+            return wrapFieldError(structFieldPath, err)
+        }
+        addColumn(column, storageValue(structFieldValue.Interface()))
+    }
+
+    if session.strict {
+        for payloadKey := range payloadMap {
+            if !matchedPayloadKeys[payloadKey] {
+                return &FieldError{Path: appendJsonPointer(path, payloadKey), Err: ErrUnknownField}
+            }
+        }
+    }
+    return nil
+}
+
+// collectNullSQLColumns sets every column a nil struct pointer is flattened to NULL, but the read-only ones.
+func (session *patchSession) collectNullSQLColumns(structType reflect.Type, columnPrefix string, addColumn func(column string, value interface{})) error {
+    for index := 0; index < structType.NumField(); index += 1 {
+        structField := structType.Field(index)
+        structFieldJsonTag, err := session.getJsonStructTag(structField)
+        if err != nil {
+            return err
+        }
+        fieldTag := parsePatchFieldTag(structField)
+        column := strings.Split(structField.Tag.Get(dbTagName), ",")[0]
+        if structFieldJsonTag == "" || column == "-" || fieldTag.readOnly {
+            continue
+        }
+        if column == "" {
+            column = structFieldJsonTag
+        }
+        column = columnPrefix + column
+
+        structFieldType := structField.Type
+        for structFieldType.Kind() == reflect.Ptr {
+            structFieldType = structFieldType.Elem()
+        }
+        if isTraversableStructType(structFieldType) {
+            err = session.collectNullSQLColumns(structFieldType, column+"_", addColumn)
+            if err != nil {
+                return err
+            }
+            continue
+        }
+        addColumn(column, nil)
+    }
+    return nil
+}
+
+// storageValue dereferences pointers, nil being NULL, and converts Optional and driver.Valuer values.
+func storageValue(value interface{}) interface{} {
+    reflectValue := reflect.ValueOf(value)
+    for reflectValue.Kind() == reflect.Ptr {
+        if reflectValue.IsNil() {
+            return nil
+        }
+        reflectValue = reflectValue.Elem()
+    }
+    if !reflectValue.IsValid() {
+        return nil
+    }
+    return changeSetValue(reflectValue.Interface())
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+    "database/sql"
+    "errors"
+    "reflect"
+    "testing"
+)
+
+type testAccountRow struct {
+    Name     string         `json:"name" db:"full_name"`
+    Age      *int           `json:"age"`
+    Nickname sql.NullString `json:"nickname"`
+    Home     testAddress    `json:"home"`
+    Work     *testAddress   `json:"work"`
+    Secret   string         `json:"secret" db:"-"`
+    Created  string         `json:"created" patch:"readonly"`
+    Version  int            `json:"version" patch:"version"`
+}
+
+func TestToSQLUpdate(t *testing.T) {
+    clause, args, err := ToSQLUpdate([]byte(`{"name": "Jane", "age": 31, "nickname": null, "secret": "x", "created": "now", "version": 3}`), reflect.TypeOf(testAccountRow{}), Postgres)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if clause != `SET "full_name" = $1, "age" = $2, "nickname" = $3` {
+        t.Fatalf("unexpected clause %s", clause)
+    }
+    if !reflect.DeepEqual(args, []interface{}{"Jane", 31, nil}) {
+        t.Fatalf("unexpected args %#v", args)
+    }
+}
+
+func TestToSQLUpdateNestedStructs(t *testing.T) {
+    clause, args, err := ToSQLUpdate([]byte(`{"home": {"city": "Paris"}, "work": {"zip": "69001"}}`), reflect.TypeOf(&testAccountRow{}), MySQL)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if clause != "SET `home_city` = ?, `work_zip` = ?" {
+        t.Fatalf("unexpected clause %s", clause)
+    }
+    if !reflect.DeepEqual(args, []interface{}{"Paris", "69001"}) {
+        t.Fatalf("unexpected args %#v", args)
+    }
+
+    clause, args, err = ToSQLUpdate([]byte(`{"work": null, "age": null}`), reflect.TypeOf(testAccountRow{}), SQLite)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if clause != `SET "age" = ?, "work_city" = ?, "work_zip" = ?` || !reflect.DeepEqual(args, []interface{}{nil, nil, nil}) {
+        t.Fatalf("a null struct pointer should set each of its columns to NULL, got %s %#v", clause, args)
+    }
+
+    _, _, err = NewPatcher(WithNullPolicy(NullRejected)).ToSQLUpdate([]byte(`{"work": null}`), reflect.TypeOf(testAccountRow{}), SQLite)
+    if !errors.Is(err, ErrNullRejected) {
+        t.Fatalf("expected ErrNullRejected, got %v", err)
+    }
+}
+
+func TestToSQLUpdateErrors(t *testing.T) {
+    _, _, err := ToSQLUpdate([]byte(`{"work": {"zip": 1}}`), reflect.TypeOf(testAccountRow{}), Postgres)
+    var fieldError *FieldError
+    if !errors.Is(err, ErrTypeMismatch) || !errors.As(err, &fieldError) || fieldError.Path != "/work/zip" {
+        t.Fatalf("expected ErrTypeMismatch at /work/zip, got %v", err)
+    }
+
+    _, _, err = NewPatcher(WithStrict(true)).ToSQLUpdate([]byte(`{"phone": "1"}`), reflect.TypeOf(testAccountRow{}), Postgres)
+    if !errors.Is(err, ErrUnknownField) {
+        t.Fatalf("expected ErrUnknownField, got %v", err)
+    }
+
+    _, _, err = NewPatcher(WithReadOnlyPolicy(ReadOnlyRejected)).ToSQLUpdate([]byte(`{"created": "now"}`), reflect.TypeOf(testAccountRow{}), Postgres)
+    if !errors.Is(err, ErrReadOnlyField) {
+        t.Fatalf("expected ErrReadOnlyField, got %v", err)
+    }
+
+    clause, args, err := ToSQLUpdate([]byte(`{}`), reflect.TypeOf(testAccountRow{}), Postgres)
+    if err != nil || clause != "" || args != nil {
+        t.Fatalf("expected an empty clause, got %v, %s, %v", err, clause, args)
+    }
+}
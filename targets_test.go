@@ -0,0 +1,75 @@
+package main
+
+import (
+    "errors"
+    "reflect"
+    "testing"
+)
+
+func TestSliceTarget(t *testing.T) {
+    users := []testUser{{Name: "John", Age: 30}}
+
+    err := PatchValues([]byte(`[{"name": "Jane"}, {"name": "Bob", "address": {"city": "Paris"}}]`), &users)
+    if err != nil {
+        t.Fatal(err)
+    }
+    expected := []testUser{{Name: "Jane"}, {Name: "Bob", Address: testAddress{City: "Paris"}}}
+    if !reflect.DeepEqual(users, expected) {
+        t.Fatalf("expected %+v, got %+v", expected, users)
+    }
+
+    err = PatchValues([]byte(`[{"name": "Ann", "age": "x"}]`), &users)
+    var fieldError *FieldError
+    if !errors.Is(err, ErrTypeMismatch) || !errors.As(err, &fieldError) || fieldError.Path != "/0/age" {
+        t.Fatalf("expected ErrTypeMismatch at /0/age, got %v", err)
+    }
+    if len(users) != 2 {
+        t.Fatalf("a failed patch should leave the slice untouched, got %+v", users)
+    }
+
+    err = PatchValues([]byte(`{"name": "Ann"}`), &users)
+    if !errors.Is(err, ErrTypeMismatch) {
+        t.Fatalf("expected ErrTypeMismatch for an object, got %v", err)
+    }
+}
+
+func TestMapTarget(t *testing.T) {
+    users := map[string]testUser{
+        "a": {Name: "John", Age: 30},
+        "b": {Name: "Bob"},
+    }
+
+    err := PatchValues([]byte(`{"a": {"age": 31}, "b": null, "c": {"name": "Ann"}}`), &users)
+    if err != nil {
+        t.Fatal(err)
+    }
+    expected := map[string]testUser{
+        "a": {Name: "John", Age: 31},
+        "c": {Name: "Ann"},
+    }
+    if !reflect.DeepEqual(users, expected) {
+        t.Fatalf("expected %+v, got %+v", expected, users)
+    }
+
+    counts := map[string]int{"a": 1}
+    err = PatchValues([]byte(`{"a": 2, "b": 3}`), &counts)
+    if err != nil || !reflect.DeepEqual(counts, map[string]int{"a": 2, "b": 3}) {
+        t.Fatalf("%v, %v", err, counts)
+    }
+
+    err = PatchValues([]byte(`{"a": "x", "c": 1}`), &counts)
+    if !errors.Is(err, ErrTypeMismatch) || len(counts) != 2 || counts["a"] != 2 {
+        t.Fatalf("a failed patch should leave the map untouched, got %v, %v", err, counts)
+    }
+}
+
+func TestUnsupportedTargets(t *testing.T) {
+    name := "John"
+    var users []testUser
+
+    for _, target := range []interface{}{name, &name, users} {
+        if err := PatchValues([]byte(`{}`), target); err == nil {
+            t.Fatalf("expected an error for %T", target)
+        }
+    }
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+    "fmt"
+    "math"
+    "reflect"
+    "time"
+)
+
+var (
+    timeType     = reflect.TypeOf(time.Time{})
+    durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// timeConverter parses strings with layout, RFC 3339 when no `patch:"layout=..."` is given.
+func timeConverter(layout string) Converter {
+    if layout == "" {
+        layout = time.RFC3339Nano
+    }
+
+    return func(targetType reflect.Type, iPayloadValue interface{}) (reflect.Value, error) {
+        if iPayloadValue == nil {
+            return reflect.Zero(targetType), nil
+        }
+
+        text, ok := iPayloadValue.(string)
+        if !ok {
+            return reflect.Value{}, fmt.Errorf("Invalid payload data for %+v: %w", targetType, ErrTypeMismatch)
+        }
+
+        parsed, err := time.Parse(layout, text)
+        if err != nil {
+            return reflect.Value{}, fmt.Errorf("Invalid payload data %q for %+v with layout %s: %w", text, targetType, layout, ErrTypeMismatch)
+        }
+        return reflect.ValueOf(parsed), nil
+    }
+}
+
+// convertDuration accepts Go duration strings such as "5m" as well as numbers of nanoseconds.
+func convertDuration(targetType reflect.Type, iPayloadValue interface{}) (reflect.Value, error) {
+    switch payloadValue := iPayloadValue.(type) {
+    case nil:
+        return reflect.Zero(targetType), nil
+    case string:
+        parsed, err := time.ParseDuration(payloadValue)
+        if err != nil {
+            return reflect.Value{}, fmt.Errorf("Invalid payload data %q for %+v: %w", payloadValue, targetType, ErrTypeMismatch)
+        }
+        return reflect.ValueOf(parsed), nil
+    case float64:
+        if payloadValue != math.Trunc(payloadValue) || payloadValue < math.MinInt64 || payloadValue >= math.MaxInt64 {
+            return reflect.Value{}, fmt.Errorf("Invalid payload data %v for %+v: %w", payloadValue, targetType, ErrTypeMismatch)
+        }
+        return reflect.ValueOf(time.Duration(payloadValue)), nil
+    }
+    return reflect.Value{}, fmt.Errorf("Invalid payload data for %+v: %w", targetType, ErrTypeMismatch)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+    "errors"
+    "reflect"
+    "testing"
+    "time"
+)
+
+type testSchedule struct {
+    StartsAt  time.Time                `json:"starts_at"`
+    Day       time.Time                `json:"day" patch:"layout=2006-01-02"`
+    EndsAt    *time.Time               `json:"ends_at"`
+    Timeout   time.Duration            `json:"timeout"`
+    Retries   []time.Duration          `json:"retries"`
+    Deadlines map[string]time.Duration `json:"deadlines"`
+}
+
+func TestTimeFields(t *testing.T) {
+    schedule := testSchedule{}
+
+    err := PatchValues([]byte(`{"starts_at": "2024-03-01T10:00:00Z", "day": "2024-03-02", "ends_at": "2024-03-03T10:00:00+06:30"}`), &schedule)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !schedule.StartsAt.Equal(time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)) || !schedule.Day.Equal(time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)) {
+        t.Fatalf("unexpected %+v", schedule)
+    }
+    if schedule.EndsAt == nil || !schedule.EndsAt.Equal(time.Date(2024, 3, 3, 3, 30, 0, 0, time.UTC)) {
+        t.Fatalf("unexpected ends_at %v", schedule.EndsAt)
+    }
+
+    err = PatchValues([]byte(`{"ends_at": null}`), &schedule)
+    if err != nil || schedule.EndsAt != nil {
+        t.Fatalf("%v, %v", err, schedule.EndsAt)
+    }
+}
+
+func TestTimeFieldsRejectOtherLayouts(t *testing.T) {
+    for payload, expectedPath := range map[string]string{
+        `{"starts_at": "2024-03-01"}`:     "/starts_at",
+        `{"day": "2024-03-01T10:00:00Z"}`: "/day",
+        `{"starts_at": 1700000000}`:       "/starts_at",
+    } {
+        err := PatchValues([]byte(payload), &testSchedule{})
+        var fieldError *FieldError
+        if !errors.Is(err, ErrTypeMismatch) || !errors.As(err, &fieldError) || fieldError.Path != expectedPath {
+            t.Fatalf("%s: expected ErrTypeMismatch at %s, got %v", payload, expectedPath, err)
+        }
+    }
+}
+
+func TestDurationFields(t *testing.T) {
+    schedule := testSchedule{}
+
+    err := PatchValues([]byte(`{"timeout": "5m", "retries": ["1s", 500], "deadlines": {"a": "1h30m"}}`), &schedule)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if schedule.Timeout != 5*time.Minute || schedule.Deadlines["a"] != 90*time.Minute {
+        t.Fatalf("unexpected %+v", schedule)
+    }
+    if !reflect.DeepEqual(schedule.Retries, []time.Duration{time.Second, 500}) {
+        t.Fatalf("unexpected retries %v", schedule.Retries)
+    }
+
+    err = PatchValues([]byte(`{"timeout": "soon"}`), &schedule)
+    if !errors.Is(err, ErrTypeMismatch) || schedule.Timeout != 5*time.Minute {
+        t.Fatalf("expected ErrTypeMismatch, got %v, %+v", err, schedule)
+    }
+}
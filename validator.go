@@ -0,0 +1,195 @@
+package main
+
+import (
+    "context"
+    "fmt"
+    "net/mail"
+    "reflect"
+    "regexp"
+    "strconv"
+    "strings"
+    "sync"
+    "unicode/utf8"
+)
+
+// Validator checks the patched copy of the target before it is written back. Returning an error discards the patch.
+type Validator interface {
+    Validate(ctx context.Context, iStructPointer interface{}) error
+}
+
+type ValidatorFunc func(ctx context.Context, iStructPointer interface{}) error
+
+func (validatorFunc ValidatorFunc) Validate(ctx context.Context, iStructPointer interface{}) error {
+    return validatorFunc(ctx, iStructPointer)
+}
+
+const validateTagName = "validate"
+
+// TagValidator validates struct fields with the `validate` tag, e.g. `validate:"required,min=1,max=100,email"`.
+// Supported rules are required, min, max, email, regex and enum (values separated by "|").
+// min and max compare numbers by value and strings, slices and maps by length.
+// email, regex and enum are skipped for empty strings, combine them with required when needed.
+type TagValidator struct {
+    // TagName names the struct tag used to build the error paths. Default is "json".
+    TagName string
+}
+
+func NewTagValidator() *TagValidator {
+    return &TagValidator{TagName: "json"}
+}
+
+func (tagValidator *TagValidator) Validate(ctx context.Context, iStructPointer interface{}) error {
+    fieldErrors := make(FieldErrors, 0)
+    tagValidator.validateReflectValue(reflect.ValueOf(iStructPointer), "", &fieldErrors)
+    if len(fieldErrors) != 0 {
+        return fieldErrors
+    }
+    return nil
+}
+
+func (tagValidator *TagValidator) validateReflectValue(reflectValue reflect.Value, path string, fieldErrors *FieldErrors) {
+    switch reflectValue.Kind() {
+    case reflect.Ptr, reflect.Interface:
+        if !reflectValue.IsNil() {
+            tagValidator.validateReflectValue(reflectValue.Elem(), path, fieldErrors)
+        }
+    case reflect.Slice, reflect.Array:
+        for index := 0; index < reflectValue.Len(); index += 1 {
+            tagValidator.validateReflectValue(reflectValue.Index(index), appendJsonPointerIndex(path, index), fieldErrors)
+        }
+    case reflect.Struct:
+        tagValidator.validateStruct(reflectValue, path, fieldErrors)
+    }
+}
+
+func (tagValidator *TagValidator) validateStruct(structReflectValue reflect.Value, path string, fieldErrors *FieldErrors) {
+    tagName := tagValidator.TagName
+    if tagName == "" {
+        tagName = "json"
+    }
+
+    for index := 0; index < structReflectValue.NumField(); index += 1 {
+        structField := structReflectValue.Type().Field(index)
+        if structField.PkgPath != "" {
+            continue
+        }
+
+        structFieldName := strings.Split(structField.Tag.Get(tagName), ",")[0]
+        if structFieldName == "" {
+            structFieldName = structField.Name
+        }
+        structFieldPath := appendJsonPointer(path, structFieldName)
+        structFieldValue := structReflectValue.Field(index)
+
+        for _, rule := range splitValidateRules(structField.Tag.Get(validateTagName)) {
+            ruleName, ruleArgument := splitPatchTagOption(rule)
+            if ruleName == "" {
+                continue
+            }
+            if validationError := validateRule(structFieldValue, ruleName, ruleArgument); validationError != nil {
+                *fieldErrors = append(*fieldErrors, &FieldError{Path: structFieldPath, Err: validationError})
+            }
+        }
+
+        tagValidator.validateReflectValue(structFieldValue, structFieldPath, fieldErrors)
+    }
+}
+
+// splitValidateRules splits a validate tag on commas. A regex rule takes the rest of the tag, commas included,
+// so it has to come last.
+func splitValidateRules(validateTag string) []string {
+    rules := strings.Split(validateTag, ",")
+    for index, rule := range rules {
+        if strings.HasPrefix(rule, "regex=") {
+            return append(rules[:index], strings.Join(rules[index:], ","))
+        }
+    }
+    return rules
+}
+
+// compiledRegexps caches the patterns of regex rules, which are the same for every value of a struct type.
+var compiledRegexps sync.Map
+
+func compileRegexp(pattern string) (*regexp.Regexp, error) {
+    if compiled, ok := compiledRegexps.Load(pattern); ok {
+        return compiled.(*regexp.Regexp), nil
+    }
+
+    compiled, err := regexp.Compile(pattern)
+    if err != nil {
+        return nil, err
+    }
+    compiledRegexps.Store(pattern, compiled)
+    return compiled, nil
+}
+
+func validateRule(reflectValue reflect.Value, ruleName string, ruleArgument string) *ValidationError {
+    switch ruleName {
+    case "required":
+        if reflectValue.IsZero() {
+            return &ValidationError{Rule: ruleName, Message: "is required"}
+        }
+    case "min", "max":
+        limit, err := strconv.ParseFloat(ruleArgument, 64)
+        if err != nil {
+            return &ValidationError{Rule: ruleName, Message: fmt.Sprintf("has an invalid %s rule %q", ruleName, ruleArgument)}
+        }
+        measure, ok := measureReflectValue(reflectValue)
+        if !ok {
+            return nil
+        }
+        if ruleName == "min" && measure < limit {
+            return &ValidationError{Rule: ruleName, Message: fmt.Sprintf("must be at least %s", ruleArgument)}
+        }
+        if ruleName == "max" && measure > limit {
+            return &ValidationError{Rule: ruleName, Message: fmt.Sprintf("must be at most %s", ruleArgument)}
+        }
+    case "email":
+        if reflectValue.Kind() != reflect.String || reflectValue.String() == "" {
+            return nil
+        }
+        address, err := mail.ParseAddress(reflectValue.String())
+        if err != nil || address.Address != reflectValue.String() {
+            return &ValidationError{Rule: ruleName, Message: "must be a valid email address"}
+        }
+    case "regex":
+        if reflectValue.Kind() != reflect.String || reflectValue.String() == "" {
+            return nil
+        }
+        compiled, err := compileRegexp(ruleArgument)
+        if err != nil {
+            return &ValidationError{Rule: ruleName, Message: fmt.Sprintf("has an invalid %s rule %q", ruleName, ruleArgument)}
+        }
+        if !compiled.MatchString(reflectValue.String()) {
+            return &ValidationError{Rule: ruleName, Message: fmt.Sprintf("must match %s", ruleArgument)}
+        }
+    case "enum":
+        if reflectValue.Kind() == reflect.String && reflectValue.String() == "" {
+            return nil
+        }
+        value := fmt.Sprint(reflectValue.Interface())
+        for _, allowedValue := range strings.Split(ruleArgument, "|") {
+            if value == allowedValue {
+                return nil
+            }
+        }
+        return &ValidationError{Rule: ruleName, Message: fmt.Sprintf("must be one of %s", strings.Replace(ruleArgument, "|", ", ", -1))}
+    }
+    return nil
+}
+
+func measureReflectValue(reflectValue reflect.Value) (float64, bool) {
+    switch reflectValue.Kind() {
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+        return float64(reflectValue.Int()), true
+    case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+        return float64(reflectValue.Uint()), true
+    case reflect.Float32, reflect.Float64:
+        return reflectValue.Float(), true
+    case reflect.String:
+        return float64(utf8.RuneCountInString(reflectValue.String())), true
+    case reflect.Slice, reflect.Map, reflect.Array:
+        return float64(reflectValue.Len()), true
+    }
+    return 0, false
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+    "context"
+    "errors"
+    "reflect"
+    "testing"
+)
+
+type testProductOption struct {
+    Code string `json:"code" validate:"regex=^[A-Z]+$"`
+}
+
+type testProduct struct {
+    Name    string              `json:"name" validate:"required,max=5"`
+    Price   int                 `json:"price" validate:"min=1,max=100"`
+    Email   string              `json:"email" validate:"email"`
+    State   string              `json:"state" validate:"enum=draft|published"`
+    Options []testProductOption `json:"options" validate:"max=2"`
+}
+
+func TestTagValidatorRules(t *testing.T) {
+    product := testProduct{Name: "Tea", Price: 10}
+    patcher := NewPatcher(WithValidator(NewTagValidator()))
+
+    err := patcher.Patch([]byte(`{"name": "", "price": 0, "email": "nope", "state": "gone", "options": [{"code": "ok"}]}`), &product)
+    var fieldErrors FieldErrors
+    if !errors.Is(err, ErrValidation) || !errors.As(err, &fieldErrors) {
+        t.Fatalf("expected FieldErrors matching ErrValidation, got %v", err)
+    }
+
+    paths := make([]string, 0, len(fieldErrors))
+    for _, fieldError := range fieldErrors {
+        paths = append(paths, fieldError.Path)
+    }
+    expectedPaths := []string{"/name", "/price", "/email", "/state", "/options/0/code"}
+    if !reflect.DeepEqual(paths, expectedPaths) {
+        t.Fatalf("expected %v, got %v", expectedPaths, paths)
+    }
+
+    var validationError *ValidationError
+    if !errors.As(fieldErrors[0].Err, &validationError) || validationError.Rule != "required" {
+        t.Fatalf("unexpected cause %v", fieldErrors[0].Err)
+    }
+    if product.Name != "Tea" || product.Price != 10 {
+        t.Fatalf("an invalid patch should leave the target untouched, got %+v", product)
+    }
+}
+
+func TestTagValidatorAcceptsValidPatch(t *testing.T) {
+    product := testProduct{Name: "Tea", Price: 10}
+    patcher := NewPatcher(WithValidator(NewTagValidator()))
+
+    err := patcher.Patch([]byte(`{"price": 100, "email": "a@b.co", "state": "draft", "options": [{"code": "XL"}]}`), &product)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if product.Price != 100 || product.Options[0].Code != "XL" {
+        t.Fatalf("unexpected %+v", product)
+    }
+
+    err = patcher.Patch([]byte(`{"name": "Coffee"}`), &product)
+    if !errors.Is(err, ErrValidation) {
+        t.Fatalf("max should compare the string length, got %v", err)
+    }
+}
+
+func TestRegexRuleTakesTheRestOfTheTag(t *testing.T) {
+    type model struct {
+        Code string `json:"code" validate:"required,regex=^[a-z]{1,3}$"`
+    }
+
+    patcher := NewPatcher(WithValidator(NewTagValidator()))
+    for payload, valid := range map[string]bool{`{"code": "abc"}`: true, `{"code": "abcd"}`: false, `{"code": ""}`: false} {
+        err := patcher.Patch([]byte(payload), &model{})
+        if valid != (err == nil) {
+            t.Fatalf("%s: unexpected %v", payload, err)
+        }
+    }
+}
+
+func TestValidatorFunc(t *testing.T) {
+    rejected := errors.New("rejected")
+    var validated interface{}
+    patcher := NewPatcher(WithValidator(ValidatorFunc(func(ctx context.Context, iStructPointer interface{}) error {
+        validated = iStructPointer
+        return rejected
+    })))
+
+    user := testUser{Name: "John"}
+    err := patcher.Patch([]byte(`{"name": "Richard"}`), &user)
+    if !errors.Is(err, rejected) {
+        t.Fatalf("expected the validator error, got %v", err)
+    }
+    if validated.(*testUser).Name != "Richard" || user.Name != "John" {
+        t.Fatalf("the validator should see the patched copy, not the target: %+v, %+v", validated, user)
+    }
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+    "context"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "reflect"
+    "strconv"
+    "strings"
+)
+
+type expectedVersionContextKey struct{}
+
+// WithExpectedVersion attaches the version the caller read, e.g. from a request header. It is compared with the
+// field tagged `patch:"version"` when the payload carries no version itself.
+func WithExpectedVersion(ctx context.Context, version interface{}) context.Context {
+    return context.WithValue(ctx, expectedVersionContextKey{}, version)
+}
+
+func ExpectedVersionFromContext(ctx context.Context) (interface{}, bool) {
+    version := ctx.Value(expectedVersionContextKey{})
+    return version, version != nil
+}
+
+// lookupVersionField returns the field of the top-level struct tagged `patch:"version"`, if any.
+func (session *patchSession) lookupVersionField(targetReflectValue reflect.Value) (reflect.Value, string, bool, error) {
+    if targetReflectValue.Kind() != reflect.Struct {
+        return reflect.Value{}, "", false, nil
+    }
+
+    for index := 0; index < targetReflectValue.NumField(); index += 1 {
+        structField := targetReflectValue.Type().Field(index)
+        if !parsePatchFieldTag(structField).version {
+            continue
+        }
+
+        structFieldJsonTag, err := session.getJsonStructTag(structField)
+        if err != nil {
+            return reflect.Value{}, "", false, err
+        }
+        return targetReflectValue.Field(index), structFieldJsonTag, true, nil
+    }
+    return reflect.Value{}, "", false, nil
+}
+
+// checkVersion compares the version sent in the payload, or else the one from WithExpectedVersion, with the target.
+func (session *patchSession) checkVersion(targetReflectValue reflect.Value, iPayloadValue interface{}) error {
+    versionReflectValue, versionJsonTag, ok, err := session.lookupVersionField(targetReflectValue)
+    if err != nil || !ok {
+        return err
+    }
+
+    expectedVersion, ok := ExpectedVersionFromContext(session.ctx)
+    if payloadMap, isMap := iPayloadValue.(map[string]interface{}); isMap && versionJsonTag != "" {
+        if payloadVersion, inPayload := payloadMap[versionJsonTag]; inPayload {
+            expectedVersion, ok = payloadVersion, true
+        }
+    }
+    if !ok {
+        return nil
+    }
+
+    actualVersion := versionReflectValue.Interface()
+    if formatVersion(expectedVersion) != formatVersion(actualVersion) {
+        return &FieldError{Path: appendJsonPointer("", versionJsonTag), Err: &VersionConflictError{Expected: expectedVersion, Actual: actualVersion}}
+    }
+    return nil
+}
+
+// formatVersion writes a version the same way whether it is a decoded JSON number, a float64 which fmt would print
+// as 1e+07, or the integer held by the field.
+func formatVersion(version interface{}) string {
+    switch value := version.(type) {
+    case float64:
+        return strconv.FormatFloat(value, 'f', -1, 64)
+    case float32:
+        return strconv.FormatFloat(float64(value), 'f', -1, 32)
+    }
+    return fmt.Sprint(version)
+}
+
+// withoutVersionKey removes the version field from a document built from a whole state, e.g. by a merge or a
+// diff, so that the version it holds is not taken for the one the caller expects.
+func (session *patchSession) withoutVersionKey(targetReflectValue reflect.Value, document interface{}) (interface{}, error) {
+    _, versionJsonTag, ok, err := session.lookupVersionField(targetReflectValue)
+    if err != nil || !ok {
+        return document, err
+    }
+
+    documentMap, isMap := document.(map[string]interface{})
+    if !isMap {
+        return document, nil
+    }
+    if _, inDocument := documentMap[versionJsonTag]; !inDocument {
+        return document, nil
+    }
+
+    strippedMap := make(map[string]interface{}, len(documentMap))
+    for key, value := range documentMap {
+        if key != versionJsonTag {
+            strippedMap[key] = value
+        }
+    }
+    return strippedMap, nil
+}
+
+// incrementVersion bumps a numeric version field once the patch changed something.
+func (session *patchSession) incrementVersion(targetReflectValue reflect.Value) error {
+    if !session.changes.HasChanges() {
+        return nil
+    }
+
+    versionReflectValue, versionJsonTag, ok, err := session.lookupVersionField(targetReflectValue)
+    if err != nil || !ok {
+        return err
+    }
+
+    oldVersion := versionReflectValue.Interface()
+    switch versionReflectValue.Kind() {
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+        versionReflectValue.SetInt(versionReflectValue.Int() + 1)
+    case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+        versionReflectValue.SetUint(versionReflectValue.Uint() + 1)
+    default:
+        return nil
+    }
+
+    versionPath := appendJsonPointer("", versionJsonTag)
+    session.changes.versionPath = versionPath
+    session.changes.record(versionPath, oldVersion, versionReflectValue.Interface())
+    return nil
+}
+
+// ETag returns a strong entity tag of the JSON form of value, quoted for an ETag header.
+func ETag(value interface{}) (string, error) {
+    encoded, err := json.Marshal(value)
+    if err != nil {
+        return "", err
+    }
+
+    sum := sha256.Sum256(encoded)
+    return `"` + hex.EncodeToString(sum[:]) + `"`, nil
+}
+
+// MatchesETag tells whether an If-Match header matches the ETag of value, using the strong comparison.
+// "*" matches any value and weak tags never match.
+func MatchesETag(ifMatch string, value interface{}) (bool, error) {
+    ifMatch = strings.TrimSpace(ifMatch)
+    if ifMatch == "" {
+        return false, errors.New("If-Match should not be empty.")
+    }
+    if ifMatch == "*" {
+        return true, nil
+    }
+
+    etag, err := ETag(value)
+    if err != nil {
+        return false, err
+    }
+    for _, candidate := range strings.Split(ifMatch, ",") {
+        if strings.TrimSpace(candidate) == etag {
+            return true, nil
+        }
+    }
+    return false, nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "testing"
+)
+
+type testDocument struct {
+    Title   string `json:"title"`
+    Body    string `json:"body"`
+    Version int    `json:"version" patch:"version"`
+}
+
+func TestVersionFromPayload(t *testing.T) {
+    document := testDocument{Title: "a", Version: 3}
+
+    err := PatchValues([]byte(`{"title": "b", "version": 2}`), &document)
+    var versionConflictError *VersionConflictError
+    if !errors.Is(err, ErrVersionConflict) || !errors.As(err, &versionConflictError) || versionConflictError.Actual != 3 {
+        t.Fatalf("expected a VersionConflictError, got %v", err)
+    }
+    if document.Title != "a" {
+        t.Fatalf("a conflicting patch should leave the target untouched, got %+v", document)
+    }
+
+    err = PatchValues([]byte(`{"title": "b", "version": 3}`), &document)
+    if err != nil || document.Title != "b" || document.Version != 4 {
+        t.Fatalf("%v, %+v", err, document)
+    }
+
+    err = PatchValues([]byte(`{"title": "b"}`), &document)
+    if err != nil || document.Version != 4 {
+        t.Fatalf("an unchanged patch should keep the version, got %v, %+v", err, document)
+    }
+}
+
+func TestLargeVersions(t *testing.T) {
+    for _, version := range []int{10000000, 1700000000000} {
+        document := testDocument{Title: "a", Version: version}
+
+        err := PatchValues([]byte(fmt.Sprintf(`{"title": "b", "version": %d}`, version)), &document)
+        if err != nil || document.Version != version+1 {
+            t.Fatalf("%d: %v, %+v", version, err, document)
+        }
+
+        err = NewPatcher().PatchContext(WithExpectedVersion(context.Background(), float64(version+1)), []byte(`{"title": "c"}`), &document)
+        if err != nil || document.Title != "c" {
+            t.Fatalf("%d: %v, %+v", version, err, document)
+        }
+    }
+}
+
+func TestDiffLeavesTheVersionOut(t *testing.T) {
+    oldDocument := testDocument{Title: "a", Version: 1}
+    newDocument := testDocument{Title: "b", Version: 2}
+
+    mergePatch, err := Diff(oldDocument, &newDocument)
+    if err != nil {
+        t.Fatal(err)
+    }
+    assertJSONEqual(t, `{"title": "b"}`, mergePatch)
+
+    err = PatchValues(mergePatch, &oldDocument)
+    if err != nil || oldDocument != newDocument {
+        t.Fatalf("%v: expected %+v, got %+v", err, newDocument, oldDocument)
+    }
+
+    operations, err := DiffOperations(testDocument{Version: 1}, testDocument{Version: 2})
+    if err != nil || len(operations) != 0 {
+        t.Fatalf("%v, %+v", err, operations)
+    }
+}
+
+func TestVersionFromContext(t *testing.T) {
+    document := testDocument{Title: "a", Version: 3}
+    patcher := NewPatcher()
+
+    err := patcher.PatchContext(WithExpectedVersion(context.Background(), 2), []byte(`{"title": "b"}`), &document)
+    if !errors.Is(err, ErrVersionConflict) {
+        t.Fatalf("expected ErrVersionConflict, got %v", err)
+    }
+
+    err = patcher.PatchContext(WithExpectedVersion(context.Background(), 3), []byte(`{"title": "b"}`), &document)
+    if err != nil || document.Version != 4 {
+        t.Fatalf("%v, %+v", err, document)
+    }
+}
+
+func TestVersionInInversePatch(t *testing.T) {
+    document := testDocument{Title: "a", Version: 1}
+
+    inversePatch, err := PatchValuesWithInverse([]byte(`{"title": "b"}`), &document)
+    if err != nil {
+        t.Fatal(err)
+    }
+    assertJSONEqual(t, `{"title": "a", "version": 2}`, inversePatch)
+
+    err = PatchValues(inversePatch, &document)
+    if err != nil || document.Title != "a" || document.Version != 3 {
+        t.Fatalf("%v, %+v", err, document)
+    }
+}
+
+func TestThreeWayMergeOfVersionedEdits(t *testing.T) {
+    base := testDocument{Title: "a", Body: "x", Version: 1}
+    mine := testDocument{Title: "b", Body: "x", Version: 2}
+    theirs := testDocument{Title: "a", Body: "y", Version: 2}
+
+    merged, err := ThreeWayMerge(base, mine, theirs, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    expected := testDocument{Title: "b", Body: "y", Version: 2}
+    if *merged.(*testDocument) != expected {
+        t.Fatalf("expected %+v, got %+v", expected, *merged.(*testDocument))
+    }
+}
+
+func TestPatchOperationsWithVersion(t *testing.T) {
+    document := testDocument{Title: "a", Version: 1}
+
+    err := PatchOperations([]byte(`[{"op": "test", "path": "/version", "value": 1}, {"op": "replace", "path": "/version", "value": 7}, {"op": "replace", "path": "/title", "value": "b"}]`), &document)
+    if err != nil || document.Title != "b" || document.Version != 2 {
+        t.Fatalf("%v, %+v", err, document)
+    }
+
+    err = PatchOperations([]byte(`[{"op": "test", "path": "/version", "value": 1}, {"op": "replace", "path": "/title", "value": "c"}]`), &document)
+    if !errors.Is(err, ErrPreconditionFailed) {
+        t.Fatalf("expected ErrPreconditionFailed, got %v", err)
+    }
+}
+
+func TestETag(t *testing.T) {
+    etag, err := ETag(testDocument{Title: "a"})
+    if err != nil {
+        t.Fatal(err)
+    }
+    otherETag, _ := ETag(testDocument{Title: "b"})
+    if etag == otherETag || etag[0] != '"' {
+        t.Fatalf("unexpected etags %s, %s", etag, otherETag)
+    }
+
+    for ifMatch, expected := range map[string]bool{
+        etag:                    true,
+        "*":                     true,
+        otherETag + ", " + etag: true,
+        otherETag:               false,
+        "W/" + etag:             false,
+    } {
+        matches, err := MatchesETag(ifMatch, testDocument{Title: "a"})
+        if err != nil || matches != expected {
+            t.Fatalf("%s: expected %v, got %v, %v", ifMatch, expected, matches, err)
+        }
+    }
+
+    _, err = MatchesETag(" ", testDocument{})
+    if err == nil {
+        t.Fatal("expected an error for an empty If-Match")
+    }
+}